@@ -0,0 +1,132 @@
+// Copyright © 2017 Sebastian Mandrean <sebastian.mandrean@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"fmt"
+	"github.com/gosuri/uitable"
+	"github.com/mandrean/go-trafikverket/pkg/analytics"
+	"github.com/spf13/cobra"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var analyzeCmd = &cobra.Command{
+	Use:   "analyze",
+	Short: "Analyze when exam occasions are released",
+	Long: `Analyze when exam occasions are released at each location, from the polls
+recorded with --store: how long before the exam occasions are released, how
+long they stay available, how often taken occasions are cancelled and at what
+time of day and day of week it's best to poll.`,
+	RunE: run(analyze),
+}
+
+func init() {
+	RootCmd.AddCommand(analyzeCmd)
+
+	analyzeCmd.Flags().IntSliceVarP(&locationIDs, "location-ids", "L", nil, "(Optional) Comma-separated location IDs. Default: all recorded locations")
+	analyzeCmd.Flags().DurationVar(&since, "since", 0, "(Optional) Only use polls from this long ago, e.g. 168h. Default: all")
+}
+
+func analyze(cmd *cobra.Command, args []string) error {
+	// check required flags
+	if recordStore == nil {
+		return required([]string{"--store"})
+	}
+
+	ids := locationIDs
+	if len(ids) == 0 {
+		var err error
+		if ids, err = recordStore.LocationIDs(); err != nil {
+			return err
+		}
+	}
+
+	var from time.Time
+	if since > 0 {
+		from = time.Now().Add(-since)
+	}
+
+	// analyze every location
+	var rs []analytics.Report
+	for _, id := range ids {
+		snaps, err := recordStore.Snapshots(id, from, time.Time{})
+		if err != nil {
+			return err
+		}
+		rs = append(rs, analytics.Analyze(id, snaps, time.Local))
+	}
+
+	// print results
+	switch Output {
+	case "wide":
+		printAnalyticsWide(rs)
+		break
+	case "json":
+		printJSON(rs)
+		break
+	case "yaml":
+		printYAML(rs)
+	default:
+		printAnalyticsWide(rs)
+	}
+
+	return nil
+}
+
+func printAnalyticsWide(rs []analytics.Report) {
+	table := uitable.New()
+	table.MaxColWidth = 50
+
+	table.AddRow("NAME", "RELEASED", "CANCELLATIONS", "LEAD TIME", "AVAILABLE FOR", "BEST POLLING HOURS", "BEST POLLING DAYS")
+	for _, r := range rs {
+		name := r.LocationName
+		if name == "" {
+			name = strconv.Itoa(r.LocationID)
+		}
+
+		var hours []string
+		for _, h := range r.BestPollingHours {
+			hours = append(hours, fmt.Sprintf("%02d:00", h))
+		}
+
+		var days []string
+		for _, d := range r.BestPollingDays {
+			days = append(days, d.String()[:3])
+		}
+
+		table.AddRow(name, r.Released, r.Cancellations, median(r.LeadTime), median(r.Availability), strings.Join(hours, ", "), strings.Join(days, ", "))
+	}
+	fmt.Println(table)
+}
+
+// median formats the median of s in days or hours, or - if there's no data
+func median(s analytics.Stats) string {
+	switch {
+	case s.Count == 0:
+		return "-"
+	case s.Median >= 48*time.Hour:
+		return fmt.Sprintf("%.1f days", s.Median.Hours()/24)
+	default:
+		return s.Median.Round(time.Minute).String()
+	}
+}
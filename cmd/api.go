@@ -0,0 +1,112 @@
+// Copyright © 2017 Sebastian Mandrean <sebastian.mandrean@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/mandrean/go-trafikverket/pkg"
+	"github.com/spf13/cobra"
+	"io"
+	"os"
+)
+
+var (
+	apiMethod string
+	apiBody   string
+	apiInput  string
+	apiRaw    bool
+)
+
+var apiCmd = &cobra.Command{
+	Use:   "api <endpoint>",
+	Short: "Send a request to a Förarprov API endpoint",
+	Long: `Send a request with a JSON body to an endpoint of ` + pkg.TRAFIKVERKET_BOKA_URL + `,
+e.g. search-information, and print the response, for exploring endpoints
+this tool doesn't wrap yet. The body is taken from --body, or from the file
+given with --input, or from stdin with --input -.
+
+  go-trafikverket api search-information -b '{"bookingSession": {"socialSecurityNumber": "199001010017", "licenceId": 5}}'
+
+Responses are pretty-printed unless --raw is given, and social security
+numbers are masked unless --no-redact is given.`,
+	Args: cobra.ExactArgs(1),
+	RunE: run(api),
+}
+
+func init() {
+	RootCmd.AddCommand(apiCmd)
+
+	apiCmd.Flags().StringVarP(&apiMethod, "method", "X", "POST", "(Optional) HTTP method")
+	apiCmd.Flags().StringVarP(&apiBody, "body", "b", "", "(Optional) JSON request body")
+	apiCmd.Flags().StringVarP(&apiInput, "input", "i", "", "(Optional) File to read the JSON request body from, or - for stdin")
+	apiCmd.Flags().BoolVar(&apiRaw, "raw", false, "(Optional) Print the response as is")
+}
+
+func api(cmd *cobra.Command, args []string) error {
+	// create client
+	tc := newClient()
+
+	// read body
+	body := []byte(apiBody)
+	if apiInput != "" {
+		if apiBody != "" {
+			return usageError("--body/-b and --input/-i can't be used together")
+		}
+		var err error
+		if apiInput == "-" {
+			body, err = io.ReadAll(os.Stdin)
+		} else {
+			body, err = os.ReadFile(apiInput)
+		}
+		if err != nil {
+			return usageError("%w", err)
+		}
+	}
+
+	// send request
+	res, err := tc.Raw(context.Background(), apiMethod, args[0], body)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	b, err := io.ReadAll(res.Body)
+	if err != nil {
+		return err
+	}
+
+	// print response
+	if !apiRaw {
+		var pretty bytes.Buffer
+		if json.Indent(&pretty, b, "", "  ") == nil {
+			b = pretty.Bytes()
+		}
+	}
+	fmt.Println(pkg.RedactString(string(b)))
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return &pkg.StatusError{StatusCode: res.StatusCode, Status: res.Status}
+	}
+
+	return nil
+}
@@ -0,0 +1,142 @@
+// Copyright © 2017 Sebastian Mandrean <sebastian.mandrean@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"github.com/gosuri/uitable"
+	"github.com/mandrean/go-trafikverket/pkg"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"os"
+	"strconv"
+)
+
+var parallelism int
+
+var batchCmd = &cobra.Command{
+	Use:   "batch <roster>",
+	Short: "Find the earliest exam occasions for every student in a roster",
+	Long: `Find the earliest exam occasions for every student in a CSV or YAML roster.
+
+Each student has the keys name, socialSecurityNumber, licenceId, locationIds,
+languageId, examinationTypeId and deadline (YYYY-MM-DD). In CSV rosters these
+are the header row and locationIds are separated by semicolons.
+
+Output formats: wide, csv, json, yaml.`,
+	Args: cobra.ExactArgs(1),
+	RunE: run(batch),
+}
+
+func init() {
+	RootCmd.AddCommand(batchCmd)
+
+	batchCmd.Flags().IntVarP(&parallelism, "parallelism", "p", 4, "(Optional) Number of students to search concurrently")
+}
+
+func batch(cmd *cobra.Command, args []string) error {
+	// create client, validating each student's social security number
+	tc := newClient(pkg.WithPersonnummerValidation())
+
+	// read roster
+	roster, err := pkg.LoadRoster(args[0])
+	if err != nil {
+		return usageError("%w", err)
+	}
+
+	// search all students
+	rs := pkg.Batch(context.Background(), tc, roster, parallelism)
+
+	// print results
+	switch Output {
+	case "wide":
+		printBatchWide(rs)
+		break
+	case "csv":
+		printBatchCSV(rs)
+		break
+	case "json":
+		printJSON(rs)
+		break
+	case "yaml":
+		printYAML(rs)
+	default:
+		printBatchWide(rs)
+	}
+
+	// fail if any search did, and report no results only if all searched successfully
+	found := false
+	var errs []error
+	for _, r := range rs {
+		found = found || len(r.Occasions) > 0
+		if r.Err != nil {
+			errs = append(errs, r.Err)
+		}
+	}
+	switch {
+	case len(errs) > 0:
+		return fmt.Errorf("%d of %d students failed, first: %w", len(errs), len(rs), errs[0])
+	case !found:
+		return noResults("no occasions found for any student")
+	}
+	return nil
+}
+
+func printBatchWide(rs []pkg.BatchResult) {
+	table := uitable.New()
+	table.MaxColWidth = 50
+
+	table.AddRow("NAME", "SOCIAL SECURITY NUMBER", "LOCATION", "DATE", "TIME", "OCCASIONS", "ERROR")
+	for _, r := range batchRows(rs) {
+		table.AddRow(r[0], r[1], r[2], r[3], r[4], r[5], r[6])
+	}
+	fmt.Println(table)
+}
+
+func printBatchCSV(rs []pkg.BatchResult) {
+	w := csv.NewWriter(os.Stdout)
+	w.Write([]string{"name", "socialSecurityNumber", "location", "date", "time", "occasions", "error"})
+	w.WriteAll(batchRows(rs))
+	if err := w.Error(); err != nil {
+		log.Errorln(err)
+	}
+}
+
+// batchRows flattens results into one row per student with their earliest occasion
+func batchRows(rs []pkg.BatchResult) [][]string {
+	var rows [][]string
+	for _, r := range rs {
+		row := []string{
+			r.Candidate.Name,
+			pkg.RedactString(r.Candidate.SocialSecurityNumber),
+			"", "", "",
+			strconv.Itoa(len(r.Occasions)),
+			r.Error,
+		}
+		if r.Earliest != nil {
+			row[2], row[3], row[4] = r.Earliest.LocationName, r.Earliest.Date, r.Earliest.Time
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
@@ -0,0 +1,140 @@
+// Copyright © 2017 Sebastian Mandrean <sebastian.mandrean@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"fmt"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+	"os"
+	"strings"
+)
+
+// envPrefix prefixes the environment variables flags can be set with
+const envPrefix = "GO_TRAFIKVERKET"
+
+// profileKeys maps the keys of a profile in the config file to the flags they set, in order of precedence.
+// List values set slice flags as is, and single value flags to their first entry.
+var profileKeys = []struct {
+	key   string
+	flags []string
+}{
+	{"ssn", []string{"social-security-number"}},
+	{"licenceId", []string{"licence-id"}},
+	{"bookingModeId", []string{"booking-mode-id"}},
+	{"ignoreDebt", []string{"ignore-debt"}},
+	{"examinationTypeId", []string{"examination-type-id"}},
+	{"locationId", []string{"location-id"}},
+	{"locations", []string{"location-ids", "location-id"}},
+	{"languageId", []string{"language-id"}},
+	{"vehicleTypeId", []string{"vehicle-type-id"}},
+	{"tachographTypeId", []string{"tachograph-type-id"}},
+	{"occasionChoiceId", []string{"occasion-choice-id"}},
+	{"output", []string{"output"}},
+}
+
+// applyConfig fills in flags that weren't set on the command line, from environment variables
+// and the selected profile. Precedence is flag > env > profile > default.
+func applyConfig(cmd *cobra.Command) error {
+	if err := applyEnv(cmd); err != nil {
+		return err
+	}
+	return applyProfile(cmd)
+}
+
+// applyEnv sets flags from their environment variables, e.g. --social-security-number from
+// GO_TRAFIKVERKET_SOCIAL_SECURITY_NUMBER
+func applyEnv(cmd *cobra.Command) error {
+	var err error
+	cmd.Flags().VisitAll(func(f *pflag.Flag) {
+		if f.Changed || err != nil {
+			return
+		}
+		name := envName(f.Name)
+		if v, ok := os.LookupEnv(name); ok {
+			if e := cmd.Flags().Set(f.Name, v); e != nil {
+				err = fmt.Errorf("invalid %v: %v", name, e)
+			}
+		}
+	})
+	return err
+}
+
+// envName returns the environment variable for the flag with the given name
+func envName(flag string) string {
+	return envPrefix + "_" + strings.ToUpper(strings.Replace(flag, "-", "_", -1))
+}
+
+// applyProfile sets flags that are still unset from the selected profile
+func applyProfile(cmd *cobra.Command) error {
+	name := profile
+	if name == "" {
+		name = viper.GetString("profile")
+	}
+	if name == "" {
+		return nil
+	}
+
+	p := viper.Sub("profiles." + name)
+	if p == nil {
+		return fmt.Errorf("profile %q not found in config file", name)
+	}
+
+	set := make(map[string]bool)
+	for _, pk := range profileKeys {
+		if !p.IsSet(pk.key) {
+			continue
+		}
+		for _, fn := range pk.flags {
+			f := cmd.Flags().Lookup(fn)
+			if f == nil || f.Changed || set[fn] {
+				continue
+			}
+			if err := f.Value.Set(profileValue(f, p.Get(pk.key))); err != nil {
+				return fmt.Errorf("profile %q: invalid %v: %v", name, pk.key, err)
+			}
+			set[fn] = true
+		}
+	}
+
+	return nil
+}
+
+// profileValue formats a config value for setting flag f
+func profileValue(f *pflag.Flag, v interface{}) string {
+	l, ok := v.([]interface{})
+	if !ok {
+		return fmt.Sprint(v)
+	}
+	if len(l) == 0 {
+		return ""
+	}
+	if !strings.HasSuffix(f.Value.Type(), "Slice") {
+		return fmt.Sprint(l[0])
+	}
+
+	s := make([]string, len(l))
+	for i, e := range l {
+		s[i] = fmt.Sprint(e)
+	}
+	return strings.Join(s, ",")
+}
@@ -0,0 +1,126 @@
+// Copyright © 2017 Sebastian Mandrean <sebastian.mandrean@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/gosuri/uitable"
+	"github.com/mandrean/go-trafikverket/pkg"
+	"github.com/mandrean/go-trafikverket/pkg/personnummer"
+	"github.com/spf13/cobra"
+	"strconv"
+)
+
+// doctorCmd represents the doctor command
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Diagnose problems with the Förarprov API",
+	Long:  ``,
+}
+
+// schemaCmd represents the doctor schema command
+var schemaCmd = &cobra.Command{
+	Use:   "schema",
+	Short: "Report how the Förarprov API responses have drifted from this tool",
+	Long: `Call licence-information, search-information and occasion-bundles and report
+fields in the responses this tool doesn't know (and drops), fields it expects
+that are missing, and fields of another type, so changes to Trafikverket's API
+are noticed before they break anything.
+
+Pass --strict to any other command to fail on such drift instead.`,
+	RunE: run(doctorSchema),
+}
+
+func init() {
+	RootCmd.AddCommand(doctorCmd)
+	doctorCmd.AddCommand(schemaCmd)
+
+	schemaCmd.Flags().StringVarP(&socialSecurityNumber, "social-security-number", "S", "", "(Required) Social security number")
+	schemaCmd.Flags().IntVarP(&licenceID, "licence-id", "t", 0, "(Optional) License ID/type, defaults to Trafikverket's default")
+	schemaCmd.Flags().IntVarP(&locationID, "location-id", "L", 0, "(Optional) Location ID to query occasions at, defaults to the first location")
+}
+
+func doctorSchema(cmd *cobra.Command, args []string) error {
+	// create client
+	tc := newClient()
+
+	// check required flag
+	if socialSecurityNumber == "" {
+		return required([]string{"--social-security-number/-S"})
+	}
+
+	// validate social security number
+	ssn, err := personnummer.Normalise(socialSecurityNumber)
+	if err != nil {
+		return err
+	}
+
+	// check every endpoint
+	bs := pkg.BookingSession{
+		SocialSecurityNumber: ssn,
+		LicenceID:            licenceID,
+	}
+	reports := tc.CheckSchemas(context.Background(), bs, locationID)
+
+	// print results
+	switch Output {
+	case "wide":
+		printSchemaReportsWide(reports)
+		break
+	case "json":
+		printJSON(reports)
+		break
+	case "yaml":
+		printYAML(reports)
+	default:
+		printSchemaReportsWide(reports)
+	}
+
+	for _, r := range reports {
+		if r.Error != "" || len(r.Drifts) > 0 {
+			return &exitError{code: ExitUpstream, kind: "upstream", err: errors.New("the Förarprov API has drifted from this tool")}
+		}
+	}
+
+	return nil
+}
+
+func printSchemaReportsWide(reports []pkg.SchemaReport) {
+	table := uitable.New()
+	table.MaxColWidth = 80
+
+	table.AddRow("ENDPOINT", "STATUS", "DRIFT", "FIELD", "DETAIL")
+	for _, r := range reports {
+		status := strconv.Itoa(r.Status)
+		switch {
+		case r.Error != "":
+			table.AddRow(r.Resource, status, "error", "", r.Error)
+		case len(r.Drifts) == 0:
+			table.AddRow(r.Resource, status, "none", "", "")
+		}
+		for _, d := range r.Drifts {
+			table.AddRow(r.Resource, status, d.Kind, d.Path, d.Detail)
+		}
+	}
+	fmt.Println(table)
+}
@@ -0,0 +1,167 @@
+// Copyright © 2017 Sebastian Mandrean <sebastian.mandrean@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/mandrean/go-trafikverket/pkg"
+	"github.com/mandrean/go-trafikverket/pkg/personnummer"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"net"
+	"net/http"
+	"os"
+)
+
+// Exit codes, documented in the root command's help
+const (
+	ExitOK        = 0
+	ExitError     = 1
+	ExitUsage     = 2
+	ExitAuth      = 3
+	ExitUpstream  = 4
+	ExitNoResults = 5
+)
+
+// exitError is an error with the exit code and kind to report it with
+type exitError struct {
+	code int
+	kind string
+	err  error
+}
+
+func (e *exitError) Error() string {
+	return e.err.Error()
+}
+
+func (e *exitError) Unwrap() error {
+	return e.err
+}
+
+// usageError is returned for invalid flags and arguments
+func usageError(format string, args ...interface{}) error {
+	return &exitError{code: ExitUsage, kind: "usage", err: fmt.Errorf(format, args...)}
+}
+
+// noResults is returned when a command found nothing
+func noResults(format string, args ...interface{}) error {
+	return &exitError{code: ExitNoResults, kind: "no_results", err: fmt.Errorf(format, args...)}
+}
+
+// required returns a usage error if any flags are missing
+func required(missing []string) error {
+	switch len(missing) {
+	case 0:
+		return nil
+	case 1:
+		return usageError("%v is required!", missing[0])
+	default:
+		return usageError("%v are required!", joinFlags(missing))
+	}
+}
+
+// joinFlags joins flag names into a list like "a, b and c"
+func joinFlags(flags []string) string {
+	s := flags[0]
+	for i := 1; i < len(flags)-1; i++ {
+		s += ", " + flags[i]
+	}
+	return s + " and " + flags[len(flags)-1]
+}
+
+// run wraps a command's RunE to classify the errors it returns for their exit code
+func run(f func(cmd *cobra.Command, args []string) error) func(cmd *cobra.Command, args []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		if err := f(cmd, args); err != nil {
+			return classify(err)
+		}
+		return nil
+	}
+}
+
+// classify wraps err in an exitError according to its cause
+func classify(err error) error {
+	var ee *exitError
+	if errors.As(err, &ee) {
+		return err
+	}
+
+	var ve *pkg.ValidationError
+	var se *pkg.StatusError
+	var de *pkg.SchemaError
+	var syn *json.SyntaxError
+	var ute *json.UnmarshalTypeError
+	var ne net.Error
+	switch {
+	case errors.As(err, &ve),
+		errors.Is(err, personnummer.ErrInvalidFormat),
+		errors.Is(err, personnummer.ErrInvalidDate),
+		errors.Is(err, personnummer.ErrInvalidChecksum),
+		errors.Is(err, pkg.ErrInvalidJSON):
+		return &exitError{code: ExitUsage, kind: "usage", err: err}
+	case errors.As(err, &se) && (se.StatusCode == http.StatusUnauthorized || se.StatusCode == http.StatusForbidden):
+		return &exitError{code: ExitAuth, kind: "auth", err: err}
+	case errors.As(err, &se), errors.As(err, &de), errors.As(err, &syn), errors.As(err, &ute),
+		errors.As(err, &ne), errors.Is(err, context.DeadlineExceeded):
+		return &exitError{code: ExitUpstream, kind: "upstream", err: err}
+	default:
+		return &exitError{code: ExitError, kind: "error", err: err}
+	}
+}
+
+// errorReport is what --error-format json prints
+type errorReport struct {
+	Error    string        `yaml:"error" json:"error"`
+	Kind     string        `yaml:"kind" json:"kind"`
+	ExitCode int           `yaml:"exitCode" json:"exitCode"`
+	Status   int           `yaml:"status,omitempty" json:"status,omitempty"`
+	Problems []pkg.Problem `yaml:"problems,omitempty" json:"problems,omitempty"`
+}
+
+// reportError prints err to stderr in the --error-format and returns the exit code for it. Errors not returned
+// by a command's RunE, e.g. for unknown flags, are usage errors.
+func reportError(err error) int {
+	var ee *exitError
+	if !errors.As(err, &ee) {
+		ee = &exitError{code: ExitUsage, kind: "usage", err: err}
+	}
+	msg := pkg.RedactString(ee.Error())
+
+	if errorFormat != "json" {
+		log.Errorln(msg)
+		return ee.code
+	}
+
+	r := errorReport{Error: msg, Kind: ee.kind, ExitCode: ee.code}
+	var se *pkg.StatusError
+	if errors.As(err, &se) {
+		r.Status = se.StatusCode
+	}
+	var ve *pkg.ValidationError
+	if errors.As(err, &ve) {
+		r.Problems = ve.Problems
+	}
+	json.NewEncoder(os.Stderr).Encode(r)
+	return ee.code
+}
@@ -0,0 +1,458 @@
+// Copyright © 2017 Sebastian Mandrean <sebastian.mandrean@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"github.com/gdamore/tcell/v2"
+	"github.com/mandrean/go-trafikverket/pkg"
+	"github.com/mandrean/go-trafikverket/pkg/metrics"
+	"github.com/mandrean/go-trafikverket/pkg/personnummer"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/rivo/tview"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+var (
+	refreshInterval time.Duration
+	metricsAddress  string
+)
+
+var findCmd = &cobra.Command{
+	Use:   "find",
+	Short: "Interactively find an exam occasion",
+	Long: `Interactively find an exam occasion, by choosing a licence, location,
+language and vehicle type step by step. Occasions are refreshed periodically
+and can be filtered by typing. Press Esc to go back a step and Ctrl-C to quit.
+
+With --metrics-address, Prometheus metrics are served on /metrics while
+finding, including the earliest occasion of every refresh.`,
+	RunE: run(find),
+}
+
+func init() {
+	RootCmd.AddCommand(findCmd)
+
+	findCmd.Flags().StringVarP(&socialSecurityNumber, "social-security-number", "S", "", "(Optional) Social security number. Prompted for if not set")
+	findCmd.Flags().DurationVarP(&refreshInterval, "refresh", "r", time.Minute, "(Optional) How often to refresh occasions")
+	findCmd.Flags().StringVar(&metricsAddress, "metrics-address", "", "(Optional) Address to serve Prometheus metrics on, e.g. localhost:9090")
+}
+
+type (
+	// finder is the state of the find wizard
+	finder struct {
+		tc    *pkg.TrafikverketClient
+		app   *tview.Application
+		pages *tview.Pages
+		// stack holds the names of the pages shown so far, for going back
+		stack []string
+
+		// selections so far
+		session pkg.BookingSession
+		info    *pkg.SearchInformationResponse
+		query   pkg.OccasionBundleQuery
+
+		// stop cancels the live refresh of occasions
+		stop context.CancelFunc
+		// observe is called with every fetched list of occasions
+		observe func(query pkg.OccasionBundleQuery, os []pkg.Occasion)
+	}
+)
+
+func find(cmd *cobra.Command, args []string) error {
+	if refreshInterval <= 0 {
+		return usageError("--refresh/-r must be positive, got %v", refreshInterval)
+	}
+
+	f := &finder{
+		app:     tview.NewApplication(),
+		pages:   tview.NewPages(),
+		stop:    func() {},
+		observe: func(pkg.OccasionBundleQuery, []pkg.Occasion) {},
+	}
+
+	// set up instrumentation
+	var opts []pkg.ClientOption
+	if metricsAddress != "" {
+		l, err := net.Listen("tcp", metricsAddress)
+		if err != nil {
+			return usageError("--metrics-address: %w", err)
+		}
+		defer l.Close()
+
+		reg := prometheus.NewRegistry()
+		m := metrics.New(reg)
+		opts = append(opts, m.ClientOption())
+		f.observe = m.ObserveOccasions
+
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", promhttp.HandlerFor(reg, promhttp.HandlerOpts{}))
+		go http.Serve(l, mux)
+	}
+	f.tc = newClient(opts...)
+
+	if socialSecurityNumber != "" {
+		ssn, err := personnummer.Normalise(socialSecurityNumber)
+		if err != nil {
+			return err
+		}
+		f.session.SocialSecurityNumber = ssn
+		f.licences()
+	} else {
+		f.ssn()
+	}
+
+	// logs would be written over the screen, so they are discarded while it is shown
+	out := log.StandardLogger().Out
+	log.SetOutput(io.Discard)
+	err := f.app.SetRoot(f.pages, true).Run()
+	log.SetOutput(out)
+	f.stop()
+	return err
+}
+
+// ssn prompts for the social security number
+func (f *finder) ssn() {
+	input := tview.NewInputField().
+		SetLabel("Social security number: ").
+		SetFieldWidth(14)
+	input.SetBorder(true).SetTitle("Who is taking the exam?")
+	input.SetDoneFunc(func(key tcell.Key) {
+		switch key {
+		case tcell.KeyEnter:
+			ssn, err := personnummer.Normalise(input.GetText())
+			if err != nil {
+				f.error(err)
+				return
+			}
+			f.session.SocialSecurityNumber = ssn
+			f.licences()
+		case tcell.KeyEscape:
+			f.back()
+		}
+	})
+
+	f.show("ssn", input)
+}
+
+// licences lets the user pick a licence from the available licence categories
+func (f *finder) licences() {
+	var lcs *[]pkg.LicenceCategory
+	f.load(func() (err error) {
+		lcs, _, err = f.tc.LicenceCategories()
+		return err
+	}, func() {
+		list := f.list("Licence", true)
+		for _, lc := range *lcs {
+			for _, l := range lc.Licences {
+				id := l.ID
+				list.AddItem(fmt.Sprintf("%v %v", l.Category, l.Name), l.Description, 0, func() {
+					f.session.LicenceID = id
+					f.locations()
+				})
+			}
+		}
+
+		f.show("licences", list)
+	})
+}
+
+// locations lets the user search for and pick an exam location
+func (f *finder) locations() {
+	var info *pkg.SearchInformationResponse
+	f.load(func() (err error) {
+		info, _, err = f.tc.SearchInformation(&pkg.SearchInformationRequest{BookingSession: f.session})
+		return err
+	}, func() {
+		f.info = info
+
+		list := f.list("Location", false)
+		filter := func(text string) {
+			list.Clear()
+			for _, l := range info.Data.Locations {
+				if !matches(text, l.Name, l.Address.City) {
+					continue
+				}
+				id := int(l.ID)
+				list.AddItem(l.Name, "", 0, func() {
+					f.query.LocationID = id
+					f.languages()
+				})
+			}
+		}
+		filter("")
+
+		search := tview.NewInputField().SetLabel("Search: ").SetChangedFunc(filter)
+		search.SetDoneFunc(func(key tcell.Key) {
+			switch key {
+			case tcell.KeyEnter, tcell.KeyTab, tcell.KeyDown:
+				f.app.SetFocus(list)
+			case tcell.KeyEscape:
+				f.back()
+			}
+		})
+
+		layout := tview.NewFlex().SetDirection(tview.FlexRow).
+			AddItem(search, 1, 0, true).
+			AddItem(list, 0, 1, false)
+		f.show("locations", layout)
+	})
+}
+
+// languages lets the user pick an exam language offered at the selected location
+func (f *finder) languages() {
+	f.query.LanguageID = int(f.info.Data.LanguageID)
+	if !f.info.Data.ShowLanguage {
+		f.vehicleTypes()
+		return
+	}
+
+	list := f.list("Language", false)
+	for _, l := range f.info.Data.Languages {
+		// languages without locations are offered everywhere
+		if len(l.LocationIDs) > 0 && !containsInt(l.LocationIDs, f.query.LocationID) {
+			continue
+		}
+		id := int(l.ID)
+		list.AddItem(l.Name, "", 0, func() {
+			f.query.LanguageID = id
+			f.vehicleTypes()
+		})
+	}
+
+	f.show("languages", list)
+}
+
+// vehicleTypes lets the user pick a vehicle type, if applicable for the licence
+func (f *finder) vehicleTypes() {
+	f.query.VehicleTypeID = int(f.info.Data.VehicleTypeID)
+	if !f.info.Data.ShowVehicleType {
+		f.occasions()
+		return
+	}
+
+	list := f.list("Vehicle type", false)
+	for _, v := range f.info.Data.VehicleTypes {
+		id := v.ID
+		list.AddItem(v.Name, "", 0, func() {
+			f.query.VehicleTypeID = id
+			f.occasions()
+		})
+	}
+
+	f.show("vehicleTypes", list)
+}
+
+// occasions shows the available occasions in a filterable table, refreshing it periodically
+func (f *finder) occasions() {
+	f.query.TachographTypeID = int(f.info.Data.TachographTypeID)
+	f.query.OccasionChoiceID = int(f.info.Data.OccasionChoiceID)
+	f.query.ExaminationTypeID = int(f.info.Data.ExaminationTypeID)
+	body := &pkg.OccasionBundlesRequest{
+		BookingSession:      f.session,
+		OccasionBundleQuery: f.query,
+	}
+
+	// only accessed from the UI goroutine
+	var os []pkg.Occasion
+	var updated time.Time
+	var refreshErr error
+
+	table := tview.NewTable().SetFixed(1, 0).SetSelectable(true, false)
+	table.SetBorder(true).SetTitle("Occasions")
+	status := tview.NewTextView()
+	search := tview.NewInputField().SetLabel("Filter: ")
+
+	render := func() {
+		table.Clear()
+		for i, h := range []string{"NAME", "TYPE", "DATE", "TIME", "COST"} {
+			table.SetCell(0, i, tview.NewTableCell(h).SetSelectable(false).SetTextColor(tcell.ColorYellow))
+		}
+		row := 1
+		for _, o := range os {
+			if !matches(search.GetText(), o.LocationName, o.Name, o.Date, o.Time) {
+				continue
+			}
+			for i, c := range []string{o.LocationName, o.Name, o.Date, o.Time, o.Cost + o.CostText} {
+				table.SetCell(row, i, tview.NewTableCell(c).SetExpansion(1))
+			}
+			row++
+		}
+		text := fmt.Sprintf("%d of %d occasions, updated %v. Esc: back, Ctrl-C: quit",
+			row-1, len(os), updated.Format("15:04:05"))
+		if refreshErr != nil {
+			text = fmt.Sprintf("Refresh failed: %v. %v", pkg.RedactString(refreshErr.Error()), text)
+		}
+		status.SetText(text)
+	}
+	search.SetChangedFunc(func(string) { render() })
+
+	back := func() {
+		f.stop()
+		f.back()
+	}
+	search.SetDoneFunc(func(key tcell.Key) {
+		switch key {
+		case tcell.KeyEnter, tcell.KeyTab, tcell.KeyDown:
+			f.app.SetFocus(table)
+		case tcell.KeyEscape:
+			back()
+		}
+	})
+	table.SetDoneFunc(func(key tcell.Key) {
+		if key == tcell.KeyEscape {
+			back()
+		}
+	})
+
+	var res *[]pkg.Occasion
+	f.load(func() (err error) {
+		res, _, err = f.tc.Occasions(body)
+		return err
+	}, func() {
+		os, updated = *res, time.Now()
+		f.observe(body.OccasionBundleQuery, os)
+		render()
+
+		layout := tview.NewFlex().SetDirection(tview.FlexRow).
+			AddItem(search, 1, 0, true).
+			AddItem(table, 0, 1, false).
+			AddItem(status, 1, 0, false)
+		f.show("occasions", layout)
+
+		// refresh in the background until the user leaves the page
+		ctx, cancel := context.WithCancel(context.Background())
+		f.stop = cancel
+		go func() {
+			t := time.NewTicker(refreshInterval)
+			defer t.Stop()
+			for {
+				select {
+				case <-t.C:
+					res, _, err := f.tc.OccasionsContext(ctx, body)
+					if ctx.Err() != nil {
+						return
+					}
+					f.app.QueueUpdateDraw(func() {
+						refreshErr = err
+						if err == nil {
+							os, updated = *res, time.Now()
+							f.observe(body.OccasionBundleQuery, os)
+						}
+						render()
+					})
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	})
+}
+
+// load shows a loading message while fetch runs in the background, then calls show on the UI goroutine
+func (f *finder) load(fetch func() error, show func()) {
+	loading := tview.NewModal().SetText("Loading...")
+	f.pages.AddPage("loading", loading, true, true)
+
+	go func() {
+		err := fetch()
+		f.app.QueueUpdateDraw(func() {
+			f.pages.RemovePage("loading")
+			if err != nil {
+				f.error(err)
+				return
+			}
+			show()
+		})
+	}()
+}
+
+// list creates a bordered list where Esc goes back a step
+func (f *finder) list(title string, secondary bool) *tview.List {
+	list := tview.NewList().ShowSecondaryText(secondary)
+	list.SetBorder(true).SetTitle(title)
+	list.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if event.Key() == tcell.KeyEscape {
+			f.back()
+			return nil
+		}
+		return event
+	})
+	return list
+}
+
+// show adds or replaces a page and switches to it
+func (f *finder) show(name string, p tview.Primitive) {
+	f.stack = append(f.stack, name)
+	f.pages.AddAndSwitchToPage(name, p, true)
+}
+
+// back switches to the previous page, or quits from the first one
+func (f *finder) back() {
+	if len(f.stack) < 2 {
+		f.app.Stop()
+		return
+	}
+	f.stack = f.stack[:len(f.stack)-1]
+	f.pages.SwitchToPage(f.stack[len(f.stack)-1])
+}
+
+// error shows err in a dialog
+func (f *finder) error(err error) {
+	modal := tview.NewModal().
+		SetText(pkg.RedactString(err.Error())).
+		AddButtons([]string{"OK"}).
+		SetDoneFunc(func(int, string) {
+			f.pages.RemovePage("error")
+		})
+	f.pages.AddPage("error", modal, true, true)
+}
+
+// matches reports whether any of fields contains text, case-insensitively
+func matches(text string, fields ...string) bool {
+	text = strings.ToLower(strings.TrimSpace(text))
+	if text == "" {
+		return true
+	}
+	for _, f := range fields {
+		if strings.Contains(strings.ToLower(f), text) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsInt(l []int, i int) bool {
+	for _, e := range l {
+		if e == i {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,133 @@
+// Copyright © 2017 Sebastian Mandrean <sebastian.mandrean@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"fmt"
+	"github.com/gosuri/uitable"
+	"github.com/mandrean/go-trafikverket/pkg/store"
+	"github.com/spf13/cobra"
+	"strings"
+	"time"
+)
+
+var since time.Duration
+
+var historyCmd = &cobra.Command{
+	Use:   "history",
+	Short: "Show when exam occasions appeared and disappeared",
+	Long: `Show when exam occasions appeared and disappeared, and at what time of day
+new occasions are released, from the polls recorded with --store.
+
+Record polls by passing --store to any command that fetches occasions, e.g.
+  go-trafikverket --store history.db list occasions -S ... -L ...`,
+	RunE: run(history),
+}
+
+type historyReport struct {
+	Changes      []store.Change `yaml:"changes"`
+	ReleaseHours [24]int        `yaml:"releaseHours"`
+}
+
+func init() {
+	RootCmd.AddCommand(historyCmd)
+
+	historyCmd.Flags().IntSliceVarP(&locationIDs, "location-ids", "L", nil, "(Optional) Comma-separated location IDs. Default: all recorded locations")
+	historyCmd.Flags().DurationVar(&since, "since", 0, "(Optional) Only use polls from this long ago, e.g. 168h. Default: all")
+}
+
+func history(cmd *cobra.Command, args []string) error {
+	// check required flags
+	if recordStore == nil {
+		return required([]string{"--store"})
+	}
+
+	ids := locationIDs
+	if len(ids) == 0 {
+		var err error
+		if ids, err = recordStore.LocationIDs(); err != nil {
+			return err
+		}
+	}
+
+	var from time.Time
+	if since > 0 {
+		from = time.Now().Add(-since)
+	}
+
+	// diff the snapshots of every location
+	var r historyReport
+	for _, id := range ids {
+		snaps, err := recordStore.Snapshots(id, from, time.Time{})
+		if err != nil {
+			return err
+		}
+		r.Changes = append(r.Changes, store.Changes(snaps)...)
+	}
+	r.ReleaseHours = store.ReleaseHours(r.Changes, time.Local)
+
+	// print results
+	switch Output {
+	case "wide":
+		printHistoryWide(r)
+		break
+	case "json":
+		printJSON(r)
+		break
+	case "yaml":
+		printYAML(r)
+	default:
+		printHistoryWide(r)
+	}
+
+	return nil
+}
+
+func printHistoryWide(r historyReport) {
+	table := uitable.New()
+	table.MaxColWidth = 50
+
+	table.AddRow("OBSERVED", "CHANGE", "NAME", "TYPE", "DATE", "TIME")
+	for _, c := range r.Changes {
+		o := c.Occasion
+		table.AddRow(c.Time.Local().Format("2006-01-02 15:04"), c.Kind, o.LocationName, o.Name, o.Date, o.Time)
+	}
+	fmt.Println(table)
+	fmt.Println()
+
+	// release time of day histogram
+	max := 0
+	for _, n := range r.ReleaseHours {
+		if n > max {
+			max = n
+		}
+	}
+	table = uitable.New()
+	table.AddRow("HOUR", "RELEASED", "")
+	for h, n := range r.ReleaseHours {
+		bar := ""
+		if max > 0 {
+			bar = strings.Repeat("█", (n*40+max-1)/max)
+		}
+		table.AddRow(fmt.Sprintf("%02d:00", h), n, bar)
+	}
+	fmt.Println(table)
+}
@@ -22,6 +22,7 @@ package cmd
 
 import (
 	"github.com/spf13/cobra"
+	"time"
 )
 
 var (
@@ -32,7 +33,9 @@ var (
 	examinationTypeID    int
 
 	startDate        string
+	until            string
 	locationID       int
+	locationIDs      []int
 	languageID       int
 	vehicleTypeID    int
 	tachographTypeID int
@@ -50,3 +53,15 @@ var listCmd = &cobra.Command{
 func init() {
 	RootCmd.AddCommand(listCmd)
 }
+
+// parseStartDate parses the optional --start-date flag
+func parseStartDate() (time.Time, error) {
+	if startDate == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.Parse(time.RFC3339, startDate)
+	if err != nil {
+		return t, usageError("--start-date/-D must be an RFC 3339 date: %w", err)
+	}
+	return t, nil
+}
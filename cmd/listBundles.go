@@ -0,0 +1,154 @@
+// Copyright © 2017 Sebastian Mandrean <sebastian.mandrean@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"github.com/gosuri/uitable"
+	"github.com/mandrean/go-trafikverket/pkg"
+	"github.com/mandrean/go-trafikverket/pkg/personnummer"
+	"github.com/spf13/cobra"
+)
+
+// bundlesCmd represents the bundles command
+var bundlesCmd = &cobra.Command{
+	Use:     "bundles",
+	Aliases: []string{"b"},
+	Short:   "List exam occasion bundles",
+	Long: `List exam occasions grouped in the bundles they are booked in, e.g. a theory test
+together with a driving test, with the total cost of each bundle.`,
+	RunE: run(bundles),
+}
+
+func init() {
+	listCmd.AddCommand(bundlesCmd)
+
+	bundlesCmd.Flags().StringVarP(&socialSecurityNumber, "social-security-number", "S", "", "(Required) Social security number")
+	bundlesCmd.Flags().IntVarP(&licenceID, "licence-id", "t", 0, "(Optional) License ID/type, defaults to Trafikverket's default")
+	bundlesCmd.Flags().IntVarP(&bookingModeID, "booking-mode-id", "B", 0, "(Optional) Booking mode ID/type")
+	bundlesCmd.Flags().BoolVarP(&ignoreDebt, "ignore-debt", "I", false, "(Optional) Ignore debt")
+
+	bundlesCmd.Flags().StringVarP(&startDate, "start-date", "D", "", "(Optional) Start date")
+	bundlesCmd.Flags().IntVarP(&locationID, "location-id", "L", 0, "(Required) Location ID")
+	bundlesCmd.Flags().IntVarP(&languageID, "language-id", "l", 0, "(Optional) Language ID, defaults to Trafikverket's default for the licence")
+	bundlesCmd.Flags().IntVarP(&vehicleTypeID, "vehicle-type-id", "V", 0, "(Optional) Vehicle type ID, defaults to Trafikverket's default for the licence")
+	bundlesCmd.Flags().IntVarP(&tachographTypeID, "tachograph-type-id", "T", 0, "(Optional) Tachograph type ID, defaults to Trafikverket's default for the licence")
+	bundlesCmd.Flags().IntVarP(&occasionChoiceID, "occasion-choice-id", "O", 0, "(Optional) Occasion choice ID, defaults to Trafikverket's default for the licence")
+	bundlesCmd.Flags().IntVarP(&examinationTypeID, "examination-type-id", "E", 0, "(Optional) Examination type ID")
+}
+
+func bundles(cmd *cobra.Command, args []string) error {
+	// create client
+	tc := newClient()
+
+	// check required flags
+	var missing []string
+	if socialSecurityNumber == "" {
+		missing = append(missing, "--social-security-number/-S")
+	}
+	if locationID == 0 {
+		missing = append(missing, "--location-id/-L")
+	}
+	if err := required(missing); err != nil {
+		return err
+	}
+
+	// validate social security number
+	ssn, err := personnummer.Normalise(socialSecurityNumber)
+	if err != nil {
+		return err
+	}
+
+	// create payload
+	t, err := parseStartDate()
+	if err != nil {
+		return err
+	}
+	body := &pkg.OccasionBundlesRequest{
+		BookingSession: pkg.BookingSession{
+			SocialSecurityNumber: ssn,
+			LicenceID:            licenceID,
+			BookingModeID:        bookingModeID,
+			IgnoreDebt:           ignoreDebt,
+			ExaminationTypeID:    examinationTypeID,
+		},
+		OccasionBundleQuery: pkg.OccasionBundleQuery{
+			StartDate:         t,
+			LocationID:        locationID,
+			LanguageID:        languageID,
+			VehicleTypeID:     vehicleTypeID,
+			TachographTypeID:  tachographTypeID,
+			OccasionChoiceID:  occasionChoiceID,
+			ExaminationTypeID: examinationTypeID,
+		},
+	}
+
+	// fill in Trafikverket's defaults and check the query before sending it, as Trafikverket only
+	// responds with an opaque error or nothing
+	body, err = pkg.ResolveQuery(context.Background(), tc, body)
+	if err != nil {
+		return err
+	}
+
+	// fetch bundles
+	bs, _, err := tc.Bundles(body)
+	if err != nil {
+		return err
+	}
+
+	// print results
+	switch Output {
+	case "wide":
+		printBundlesWide(bs)
+		break
+	case "json":
+		printJSON(bs)
+		break
+	case "yaml":
+		printYAML(bs)
+	default:
+		printBundlesWide(bs)
+	}
+
+	if len(*bs) == 0 {
+		return noResults("no bundles found")
+	}
+	return nil
+}
+
+func printBundlesWide(bs *[]pkg.Bundle) {
+	table := uitable.New()
+	table.MaxColWidth = 50
+
+	// one row per occasion, with the bundle and its cost on the first
+	table.AddRow("BUNDLE", "NAME", "TYPE", "DATE", "TIME", "COST")
+	for i, b := range *bs {
+		for j, o := range b.Occasions {
+			bundle, cost := "", ""
+			if j == 0 {
+				bundle, cost = fmt.Sprint(i+1), b.Cost
+			}
+			table.AddRow(bundle, o.LocationName, o.Name, o.Date, o.Time, cost)
+		}
+	}
+	fmt.Println(table)
+}
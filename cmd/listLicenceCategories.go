@@ -24,7 +24,6 @@ import (
 	"fmt"
 	"github.com/gosuri/uitable"
 	"github.com/mandrean/go-trafikverket/pkg"
-	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 )
 
@@ -34,22 +33,21 @@ var licenceCategoriesCmd = &cobra.Command{
 	Aliases: []string{"licenseCategories", "lc"},
 	Short:   "List licence categories",
 	Long:    ``,
-	Run:     licenceCategories,
+	RunE:    run(licenceCategories),
 }
 
 func init() {
 	listCmd.AddCommand(licenceCategoriesCmd)
 }
 
-func licenceCategories(cmd *cobra.Command, args []string) {
+func licenceCategories(cmd *cobra.Command, args []string) error {
 	// create client
-	tc := pkg.NewClient()
+	tc := newClient()
 
 	// fetch licence categories
 	lcs, _, err := tc.LicenceCategories()
 	if err != nil {
-		log.Errorln(err)
-		return
+		return err
 	}
 
 	// print results
@@ -65,6 +63,8 @@ func licenceCategories(cmd *cobra.Command, args []string) {
 	default:
 		printLicenceCategories(lcs)
 	}
+
+	return nil
 }
 
 func printLicenceCategories(lcs *[]pkg.LicenceCategory) {
@@ -21,10 +21,11 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"github.com/gosuri/uitable"
 	"github.com/mandrean/go-trafikverket/pkg"
-	log "github.com/sirupsen/logrus"
+	"github.com/mandrean/go-trafikverket/pkg/personnummer"
 	"github.com/spf13/cobra"
 )
 
@@ -34,33 +35,45 @@ var locationsCmd = &cobra.Command{
 	Aliases: []string{"l"},
 	Short:   "List exam locations",
 	Long:    ``,
-	Run:     locations,
+	RunE:    run(locations),
 }
 
 func init() {
 	listCmd.AddCommand(locationsCmd)
 
 	locationsCmd.Flags().StringVarP(&socialSecurityNumber, "social-security-number", "S", "", "(Required) Social security number")
-	locationsCmd.Flags().IntVarP(&licenceID, "licence-id", "t", 5, "(Optional) License ID/type")
+	locationsCmd.Flags().IntVarP(&licenceID, "licence-id", "t", 0, "(Optional) License ID/type, defaults to Trafikverket's default")
 	locationsCmd.Flags().IntVarP(&bookingModeID, "booking-mode-id", "B", 0, "(Optional) Booking mode ID/type")
 	locationsCmd.Flags().BoolVarP(&ignoreDebt, "ignore-debt", "I", false, "(Optional) Ignore debt")
 	locationsCmd.Flags().IntVarP(&examinationTypeID, "examination-type-id", "E", 0, "(Optional) Examination ID/type")
 }
 
-func locations(cmd *cobra.Command, args []string) {
+func locations(cmd *cobra.Command, args []string) error {
 	// create client
-	tc := pkg.NewClient()
+	tc := newClient()
 
 	// check required flag
 	if socialSecurityNumber == "" {
-		log.Errorln("--social-security-number/-S is required!")
-		return
+		return required([]string{"--social-security-number/-S"})
+	}
+
+	// validate social security number
+	ssn, err := personnummer.Normalise(socialSecurityNumber)
+	if err != nil {
+		return err
+	}
+
+	// use Trafikverket's default licence unless specified
+	if licenceID == 0 {
+		if licenceID, err = pkg.DefaultLicence(context.Background(), tc); err != nil {
+			return err
+		}
 	}
 
 	// create payload
 	body := &pkg.SearchInformationRequest{
 		BookingSession: pkg.BookingSession{
-			SocialSecurityNumber: socialSecurityNumber,
+			SocialSecurityNumber: ssn,
 			LicenceID:            licenceID,
 			BookingModeID:        bookingModeID,
 			IgnoreDebt:           ignoreDebt,
@@ -71,8 +84,7 @@ func locations(cmd *cobra.Command, args []string) {
 	// fetch locations
 	ls, _, err := tc.Locations(body)
 	if err != nil {
-		log.Errorln(err)
-		return
+		return err
 	}
 
 	// print results
@@ -88,6 +100,11 @@ func locations(cmd *cobra.Command, args []string) {
 	default:
 		printLocationsWide(ls)
 	}
+
+	if len(*ls) == 0 {
+		return noResults("no locations found")
+	}
+	return nil
 }
 
 func printLocationsWide(ls *[]pkg.Location) {
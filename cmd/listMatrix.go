@@ -0,0 +1,168 @@
+// Copyright © 2017 Sebastian Mandrean <sebastian.mandrean@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"github.com/gosuri/uitable"
+	"github.com/mandrean/go-trafikverket/pkg"
+	"github.com/mandrean/go-trafikverket/pkg/personnummer"
+	"github.com/spf13/cobra"
+	"strings"
+)
+
+// the matrix filters default to matching anything, unlike the shared query flags
+var (
+	matrixLanguageID    int
+	matrixVehicleTypeID int
+)
+
+// matrixCmd represents the matrix command
+var matrixCmd = &cobra.Command{
+	Use:     "matrix",
+	Aliases: []string{"m"},
+	Short:   "List which languages and vehicle types each location offers per examination type",
+	Long: `List which languages and vehicle types each location offers per examination
+type, optionally filtered, e.g. to find where the theory test can be taken in
+a given language:
+
+  go-trafikverket list matrix -S 19900101-0017 -E 3 -l 4 -V 1
+
+Trafikverket only tells which vehicle types the licence offers for each
+examination type, not per location, so those are listed for every location.`,
+	RunE: run(matrix),
+}
+
+func init() {
+	listCmd.AddCommand(matrixCmd)
+
+	matrixCmd.Flags().StringVarP(&socialSecurityNumber, "social-security-number", "S", "", "(Required) Social security number")
+	matrixCmd.Flags().IntVarP(&licenceID, "licence-id", "t", 0, "(Optional) License ID/type, defaults to Trafikverket's default")
+	matrixCmd.Flags().IntVarP(&bookingModeID, "booking-mode-id", "B", 0, "(Optional) Booking mode ID/type")
+	matrixCmd.Flags().BoolVarP(&ignoreDebt, "ignore-debt", "I", false, "(Optional) Ignore debt")
+
+	matrixCmd.Flags().IntVarP(&locationID, "location-id", "L", 0, "(Optional) Only show this location")
+	matrixCmd.Flags().IntVarP(&examinationTypeID, "examination-type-id", "E", 0, "(Optional) Only show this examination type")
+	matrixCmd.Flags().IntVarP(&matrixLanguageID, "language-id", "l", 0, "(Optional) Only show this language")
+	matrixCmd.Flags().IntVarP(&matrixVehicleTypeID, "vehicle-type-id", "V", 0, "(Optional) Only show this vehicle type")
+}
+
+func matrix(cmd *cobra.Command, args []string) error {
+	// create client
+	tc := newClient()
+
+	// check required flag
+	if socialSecurityNumber == "" {
+		return required([]string{"--social-security-number/-S"})
+	}
+
+	// validate social security number
+	ssn, err := personnummer.Normalise(socialSecurityNumber)
+	if err != nil {
+		return err
+	}
+
+	// use Trafikverket's default licence unless specified
+	if licenceID == 0 {
+		if licenceID, err = pkg.DefaultLicence(context.Background(), tc); err != nil {
+			return err
+		}
+	}
+
+	// fetch capabilities
+	bs := pkg.BookingSession{
+		SocialSecurityNumber: ssn,
+		LicenceID:            licenceID,
+		BookingModeID:        bookingModeID,
+		IgnoreDebt:           ignoreDebt,
+	}
+	cs, err := pkg.Capabilities(context.Background(), tc, bs)
+	if err != nil {
+		return err
+	}
+	cs = pkg.FilterCapabilities(cs, pkg.CapabilityFilter{
+		LocationID:        locationID,
+		ExaminationTypeID: examinationTypeID,
+		LanguageID:        matrixLanguageID,
+		VehicleTypeID:     matrixVehicleTypeID,
+	})
+
+	// print results
+	switch Output {
+	case "wide":
+		printMatrixWide(cs)
+		break
+	case "json":
+		printJSON(cs)
+		break
+	case "yaml":
+		printYAML(cs)
+	default:
+		printMatrixWide(cs)
+	}
+
+	if len(cs) == 0 {
+		return noResults("no locations offer the examination")
+	}
+	return nil
+}
+
+func printMatrixWide(cs []pkg.Capability) {
+	table := uitable.New()
+	table.MaxColWidth = 50
+	table.Wrap = true
+
+	// one row per location and examination type
+	type row struct {
+		location, examinationType string
+		languages, vehicleTypes   []string
+	}
+	var rows []*row
+	index := make(map[[2]int]*row)
+	for _, c := range cs {
+		k := [2]int{c.LocationID, c.ExaminationTypeID}
+		r, ok := index[k]
+		if !ok {
+			r = &row{location: c.LocationName, examinationType: c.ExaminationTypeName}
+			index[k] = r
+			rows = append(rows, r)
+		}
+		r.languages = appendUnique(r.languages, c.LanguageName)
+		r.vehicleTypes = appendUnique(r.vehicleTypes, c.VehicleTypeName)
+	}
+
+	table.AddRow("LOCATION", "EXAMINATION TYPE", "LANGUAGES", "VEHICLE TYPES")
+	for _, r := range rows {
+		table.AddRow(r.location, r.examinationType, strings.Join(r.languages, ", "), strings.Join(r.vehicleTypes, ", "))
+	}
+	fmt.Println(table)
+}
+
+// appendUnique appends s to ss unless it is already there
+func appendUnique(ss []string, s string) []string {
+	for _, t := range ss {
+		if t == s {
+			return ss
+		}
+	}
+	return append(ss, s)
+}
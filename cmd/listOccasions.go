@@ -21,10 +21,11 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"github.com/gosuri/uitable"
 	"github.com/mandrean/go-trafikverket/pkg"
-	log "github.com/sirupsen/logrus"
+	"github.com/mandrean/go-trafikverket/pkg/personnummer"
 	"github.com/spf13/cobra"
 	"time"
 )
@@ -35,49 +36,71 @@ var occasionsCmd = &cobra.Command{
 	Aliases: []string{"o"},
 	Short:   "List exam occasions",
 	Long:    ``,
-	Run:     occasions,
+	RunE:    run(occasions),
 }
 
 func init() {
 	listCmd.AddCommand(occasionsCmd)
 
 	occasionsCmd.Flags().StringVarP(&socialSecurityNumber, "social-security-number", "S", "", "(Required) Social security number")
-	occasionsCmd.Flags().IntVarP(&licenceID, "licence-id", "t", 5, "(Optional) License ID/type")
+	occasionsCmd.Flags().IntVarP(&licenceID, "licence-id", "t", 0, "(Optional) License ID/type, defaults to Trafikverket's default")
 	occasionsCmd.Flags().IntVarP(&bookingModeID, "booking-mode-id", "B", 0, "(Optional) Booking mode ID/type")
 	occasionsCmd.Flags().BoolVarP(&ignoreDebt, "ignore-debt", "I", false, "(Optional) Ignore debt")
 
 	occasionsCmd.Flags().StringVarP(&startDate, "start-date", "D", "", "(Optional) Start date")
+	occasionsCmd.Flags().StringVarP(&until, "until", "U", "", "(Optional) Page through the calendar until this date")
 	occasionsCmd.Flags().IntVarP(&locationID, "location-id", "L", 0, "(Required) Location ID")
-	occasionsCmd.Flags().IntVarP(&languageID, "language-id", "l", 13, "(Optional) Language ID")
-	occasionsCmd.Flags().IntVarP(&vehicleTypeID, "vehicle-type-id", "V", 1, "(Optional) Vehicle type ID")
-	occasionsCmd.Flags().IntVarP(&tachographTypeID, "tachograph-type-id", "T", 1, "(Optional) Tachograph type ID")
-	occasionsCmd.Flags().IntVarP(&occasionChoiceID, "occasion-choice-id", "O", 1, "(Optional) Occasion choice ID")
+	occasionsCmd.Flags().IntVarP(&languageID, "language-id", "l", 0, "(Optional) Language ID, defaults to Trafikverket's default for the licence")
+	occasionsCmd.Flags().IntVarP(&vehicleTypeID, "vehicle-type-id", "V", 0, "(Optional) Vehicle type ID, defaults to Trafikverket's default for the licence")
+	occasionsCmd.Flags().IntVarP(&tachographTypeID, "tachograph-type-id", "T", 0, "(Optional) Tachograph type ID, defaults to Trafikverket's default for the licence")
+	occasionsCmd.Flags().IntVarP(&occasionChoiceID, "occasion-choice-id", "O", 0, "(Optional) Occasion choice ID, defaults to Trafikverket's default for the licence")
 	occasionsCmd.Flags().IntVarP(&examinationTypeID, "examination-type-id", "E", 0, "(Optional) Examination type ID")
+
+	addRankFlags(occasionsCmd)
 }
 
-func occasions(cmd *cobra.Command, args []string) {
+func occasions(cmd *cobra.Command, args []string) error {
 	// create client
-	tc := pkg.NewClient()
+	tc := newClient()
 
 	// check required flags
-	missing := false
+	var missing []string
 	if socialSecurityNumber == "" {
-		log.Errorln("--social-security-number/-S is required!")
-		missing = true
+		missing = append(missing, "--social-security-number/-S")
 	}
 	if locationID == 0 {
-		log.Errorln("--location-id/-L is required!")
-		missing = true
+		missing = append(missing, "--location-id/-L")
+	}
+	if err := required(missing); err != nil {
+		return err
+	}
+
+	// validate social security number
+	ssn, err := personnummer.Normalise(socialSecurityNumber)
+	if err != nil {
+		return err
+	}
+
+	// parse the remaining flags before making any requests
+	t, err := parseStartDate()
+	if err != nil {
+		return err
+	}
+	var to time.Time
+	if until != "" {
+		if to, err = time.Parse(time.RFC3339, until); err != nil {
+			return usageError("--until/-U must be an RFC 3339 date: %w", err)
+		}
 	}
-	if missing {
-		return
+	opts, err := rankOptions()
+	if err != nil {
+		return err
 	}
 
 	// create payload
-	t, _ := time.Parse(time.RFC3339, startDate)
 	body := &pkg.OccasionBundlesRequest{
 		BookingSession: pkg.BookingSession{
-			SocialSecurityNumber: socialSecurityNumber,
+			SocialSecurityNumber: ssn,
 			LicenceID:            licenceID,
 			BookingModeID:        bookingModeID,
 			IgnoreDebt:           ignoreDebt,
@@ -94,14 +117,49 @@ func occasions(cmd *cobra.Command, args []string) {
 		},
 	}
 
-	// fetch occasions
-	os, _, err := tc.Occasions(body)
+	// fill in Trafikverket's defaults and check the query before sending it, as Trafikverket only
+	// responds with an opaque error or nothing
+	body, err = pkg.ResolveQuery(context.Background(), tc, body)
 	if err != nil {
-		log.Errorln(err)
-		return
+		return err
+	}
+
+	// fetch occasions
+	var os *[]pkg.Occasion
+	if until != "" {
+		from := t
+		if from.IsZero() {
+			from = time.Now()
+		}
+		between, err := pkg.OccasionsBetween(context.Background(), tc, body, from, to)
+		if err != nil {
+			return err
+		}
+		os = &between
+	} else {
+		os, _, err = tc.Occasions(body)
+		if err != nil {
+			return err
+		}
+	}
+
+	// print results, ranked if requested
+	if rank {
+		if err := rankLocations(tc, body.BookingSession, &opts); err != nil {
+			return err
+		}
+		printRankedOccasions(pkg.Rank(*os, opts))
+	} else {
+		printOccasions(os)
+	}
+
+	if len(*os) == 0 {
+		return noResults("no occasions found")
 	}
+	return nil
+}
 
-	// print results
+func printOccasions(os *[]pkg.Occasion) {
 	switch Output {
 	case "wide":
 		printOccasionsWide(os)
@@ -126,3 +184,29 @@ func printOccasionsWide(os *[]pkg.Occasion) {
 	}
 	fmt.Println(table)
 }
+
+func printRankedOccasions(ranked []pkg.RankedOccasion) {
+	switch Output {
+	case "wide":
+		printRankedOccasionsWide(ranked)
+		break
+	case "json":
+		printJSON(ranked)
+		break
+	case "yaml":
+		printYAML(ranked)
+	default:
+		printRankedOccasionsWide(ranked)
+	}
+}
+
+func printRankedOccasionsWide(ranked []pkg.RankedOccasion) {
+	table := uitable.New()
+	table.MaxColWidth = 50
+
+	table.AddRow("NAME", "TYPE", "DATE", "TIME", "COST", "SCORE")
+	for _, o := range ranked {
+		table.AddRow(o.LocationName, o.Name, o.Date, o.Time, o.Cost+o.CostText, fmt.Sprintf("%.2f", o.Score))
+	}
+	fmt.Println(table)
+}
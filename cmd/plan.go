@@ -0,0 +1,196 @@
+// Copyright © 2017 Sebastian Mandrean <sebastian.mandrean@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"github.com/gosuri/uitable"
+	"github.com/mandrean/go-trafikverket/pkg"
+	"github.com/mandrean/go-trafikverket/pkg/personnummer"
+	"github.com/spf13/cobra"
+	"time"
+)
+
+var (
+	theoryExaminationTypeID  int
+	drivingExaminationTypeID int
+	minGap                   time.Duration
+	maxGap                   time.Duration
+	maxDistance              float64
+	limit                    int
+)
+
+var planCmd = &cobra.Command{
+	Use:   "plan",
+	Short: "Plan a theory test followed by a driving test",
+	Long: `Propose pairs of a theory test (kunskapsprov) followed by a driving test
+(körprov) at the given locations, ranked by the earliest completion date.
+
+The gap between the tests is bounded by --min-gap and --max-gap, and with
+--max-distance the driving test may be at another location within that many
+kilometres of the theory test.`,
+	RunE: run(plan),
+}
+
+func init() {
+	RootCmd.AddCommand(planCmd)
+
+	planCmd.Flags().StringVarP(&socialSecurityNumber, "social-security-number", "S", "", "(Required) Social security number")
+	planCmd.Flags().IntVarP(&licenceID, "licence-id", "t", 0, "(Optional) License ID/type, defaults to Trafikverket's default")
+	planCmd.Flags().IntVarP(&bookingModeID, "booking-mode-id", "B", 0, "(Optional) Booking mode ID/type")
+	planCmd.Flags().BoolVarP(&ignoreDebt, "ignore-debt", "I", false, "(Optional) Ignore debt")
+
+	planCmd.Flags().StringVarP(&startDate, "start-date", "D", "", "(Optional) Start date")
+	planCmd.Flags().StringVarP(&until, "until", "U", "", "(Optional) Page through the calendar until this date")
+	planCmd.Flags().IntSliceVarP(&locationIDs, "location-ids", "L", nil, "(Required) Comma-separated location IDs")
+	planCmd.Flags().IntVarP(&languageID, "language-id", "l", 0, "(Optional) Language ID, defaults to Trafikverket's default for the licence")
+	planCmd.Flags().IntVarP(&vehicleTypeID, "vehicle-type-id", "V", 0, "(Optional) Vehicle type ID, defaults to Trafikverket's default for the licence")
+	planCmd.Flags().IntVarP(&tachographTypeID, "tachograph-type-id", "T", 0, "(Optional) Tachograph type ID, defaults to Trafikverket's default for the licence")
+	planCmd.Flags().IntVarP(&occasionChoiceID, "occasion-choice-id", "O", 0, "(Optional) Occasion choice ID, defaults to Trafikverket's default for the licence")
+	planCmd.Flags().IntVar(&theoryExaminationTypeID, "theory-examination-type-id", 0, "(Optional) Examination type ID of the theory test, defaults to the licence's kunskapsprov")
+	planCmd.Flags().IntVar(&drivingExaminationTypeID, "driving-examination-type-id", 0, "(Optional) Examination type ID of the driving test, defaults to the licence's körprov")
+
+	planCmd.Flags().DurationVar(&minGap, "min-gap", 0, "(Optional) Minimum time between the theory and driving test")
+	planCmd.Flags().DurationVar(&maxGap, "max-gap", 0, "(Optional) Maximum time between the theory and driving test, 0 for no limit")
+	planCmd.Flags().Float64Var(&maxDistance, "max-distance", 0, "(Optional) Maximum distance in km between the theory and driving test locations, 0 for the same location")
+	planCmd.Flags().IntVarP(&limit, "limit", "n", 10, "(Optional) Number of pairs to show, 0 for all")
+	planCmd.Flags().IntVarP(&parallelism, "parallelism", "p", 4, "(Optional) Number of locations to query concurrently")
+}
+
+func plan(cmd *cobra.Command, args []string) error {
+	// create client
+	tc := newClient()
+
+	// check required flags
+	var missing []string
+	if socialSecurityNumber == "" {
+		missing = append(missing, "--social-security-number/-S")
+	}
+	if len(locationIDs) == 0 {
+		missing = append(missing, "--location-ids/-L")
+	}
+	if err := required(missing); err != nil {
+		return err
+	}
+
+	// validate social security number
+	ssn, err := personnummer.Normalise(socialSecurityNumber)
+	if err != nil {
+		return err
+	}
+
+	opts := pkg.PlanOptions{
+		LocationIDs:              locationIDs,
+		TheoryExaminationTypeID:  theoryExaminationTypeID,
+		DrivingExaminationTypeID: drivingExaminationTypeID,
+		MinGap:                   minGap,
+		MaxGap:                   maxGap,
+		MaxDistance:              maxDistance,
+		Parallelism:              parallelism,
+	}
+	if until != "" {
+		if opts.Until, err = time.Parse(time.RFC3339, until); err != nil {
+			return usageError("--until/-U must be an RFC 3339 date: %w", err)
+		}
+	}
+
+	// create payload template
+	t, err := parseStartDate()
+	if err != nil {
+		return err
+	}
+	body := &pkg.OccasionBundlesRequest{
+		BookingSession: pkg.BookingSession{
+			SocialSecurityNumber: ssn,
+			LicenceID:            licenceID,
+			BookingModeID:        bookingModeID,
+			IgnoreDebt:           ignoreDebt,
+		},
+		OccasionBundleQuery: pkg.OccasionBundleQuery{
+			StartDate:        t,
+			LanguageID:       languageID,
+			VehicleTypeID:    vehicleTypeID,
+			TachographTypeID: tachographTypeID,
+			OccasionChoiceID: occasionChoiceID,
+		},
+	}
+
+	// fill in Trafikverket's defaults, checking the query at the first location
+	body.OccasionBundleQuery.LocationID = locationIDs[0]
+	body, err = pkg.ResolveQuery(context.Background(), tc, body)
+	if err != nil {
+		return err
+	}
+
+	// plan pairs
+	pairs, err := pkg.Plan(context.Background(), tc, body, opts)
+	if err != nil {
+		return err
+	}
+	if limit > 0 && len(pairs) > limit {
+		pairs = pairs[:limit]
+	}
+
+	// print results
+	switch Output {
+	case "wide":
+		printPlanWide(pairs)
+		break
+	case "json":
+		printJSON(pairs)
+		break
+	case "yaml":
+		printYAML(pairs)
+	default:
+		printPlanWide(pairs)
+	}
+
+	if len(pairs) == 0 {
+		return noResults("no theory and driving test pairs found")
+	}
+	return nil
+}
+
+func printPlanWide(pairs []pkg.Pair) {
+	table := uitable.New()
+	table.MaxColWidth = 50
+
+	table.AddRow("THEORY", "DATE", "TIME", "DRIVING", "DATE", "TIME", "GAP", "DISTANCE")
+	for _, p := range pairs {
+		table.AddRow(
+			p.Theory.LocationName, p.Theory.Date, p.Theory.Time,
+			p.Driving.LocationName, p.Driving.Date, p.Driving.Time,
+			formatGap(p.Gap), fmt.Sprintf("%.0f km", p.Distance),
+		)
+	}
+	fmt.Println(table)
+}
+
+// formatGap formats d in days and hours
+func formatGap(d time.Duration) string {
+	days := int(d / (24 * time.Hour))
+	hours := int((d % (24 * time.Hour)) / time.Hour)
+	if days == 0 {
+		return fmt.Sprintf("%dh", hours)
+	}
+	return fmt.Sprintf("%dd %dh", days, hours)
+}
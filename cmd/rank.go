@@ -0,0 +1,144 @@
+// Copyright © 2017 Sebastian Mandrean <sebastian.mandrean@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"fmt"
+	"github.com/mandrean/go-trafikverket/pkg"
+	"github.com/spf13/cobra"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	rank           bool
+	weights        = pkg.DefaultRankWeights
+	home           string
+	weekdays       []string
+	preferredTimes string
+)
+
+// addRankFlags adds the flags for ranking occasions to cmd
+func addRankFlags(cmd *cobra.Command) {
+	cmd.Flags().BoolVar(&rank, "rank", false, "(Optional) Rank the occasions by a weighted score instead of date")
+	cmd.Flags().Float64Var(&weights.Soon, "weight-soon", weights.Soon, "(Optional) Weight of how soon an occasion is when ranking")
+	cmd.Flags().Float64Var(&weights.Distance, "weight-distance", weights.Distance, "(Optional) Weight of the distance from --home when ranking")
+	cmd.Flags().Float64Var(&weights.Cost, "weight-cost", weights.Cost, "(Optional) Weight of the cost when ranking")
+	cmd.Flags().Float64Var(&weights.Preferred, "weight-preferred", weights.Preferred, "(Optional) Weight of --weekdays and --times when ranking")
+	cmd.Flags().StringVar(&home, "home", "", "(Optional) Home coordinates as latitude,longitude for ranking by distance")
+	cmd.Flags().StringSliceVar(&weekdays, "weekdays", nil, "(Optional) Comma-separated preferred weekdays, e.g. mon,tue")
+	cmd.Flags().StringVar(&preferredTimes, "times", "", "(Optional) Preferred time of day, e.g. 08:00-12:00")
+}
+
+// rankOptions builds the ranking options from the flags, failing with a usage error before any request
+// is made. The location coordinates are looked up separately, see rankLocations.
+func rankOptions() (pkg.RankOptions, error) {
+	opts := pkg.RankOptions{Weights: weights}
+
+	for _, w := range []struct {
+		flag string
+		v    float64
+	}{
+		{"--weight-soon", weights.Soon},
+		{"--weight-distance", weights.Distance},
+		{"--weight-cost", weights.Cost},
+		{"--weight-preferred", weights.Preferred},
+	} {
+		if w.v < 0 {
+			return opts, usageError("%v must not be negative, got %v", w.flag, w.v)
+		}
+	}
+
+	for _, d := range weekdays {
+		wd, err := parseWeekday(d)
+		if err != nil {
+			return opts, usageError("%w", err)
+		}
+		opts.Weekdays = append(opts.Weekdays, wd)
+	}
+
+	if preferredTimes != "" {
+		from, to, ok := strings.Cut(preferredTimes, "-")
+		if !ok {
+			return opts, usageError("--times must be a range like 08:00-12:00, got %q", preferredTimes)
+		}
+		var err error
+		if opts.After, err = parseTimeOfDay(from); err != nil {
+			return opts, usageError("%w", err)
+		}
+		if opts.Before, err = parseTimeOfDay(to); err != nil {
+			return opts, usageError("%w", err)
+		}
+	}
+
+	if home != "" {
+		lat, lon, ok := strings.Cut(home, ",")
+		if !ok {
+			return opts, usageError("--home must be latitude,longitude, got %q", home)
+		}
+		var err error
+		if opts.Home.Latitude, err = strconv.ParseFloat(strings.TrimSpace(lat), 64); err != nil || math.Abs(opts.Home.Latitude) > 90 {
+			return opts, usageError("invalid --home latitude %q, expected -90 to 90", strings.TrimSpace(lat))
+		}
+		if opts.Home.Longitude, err = strconv.ParseFloat(strings.TrimSpace(lon), 64); err != nil || math.Abs(opts.Home.Longitude) > 180 {
+			return opts, usageError("invalid --home longitude %q, expected -180 to 180", strings.TrimSpace(lon))
+		}
+	}
+
+	return opts, nil
+}
+
+// rankLocations looks up the location coordinates with tc when ranking by distance from --home
+func rankLocations(tc pkg.Client, bs pkg.BookingSession, opts *pkg.RankOptions) error {
+	if opts.Home.IsZero() {
+		return nil
+	}
+
+	ls, _, err := tc.Locations(&pkg.SearchInformationRequest{BookingSession: bs})
+	if err != nil {
+		return err
+	}
+	opts.Locations = *ls
+	return nil
+}
+
+// parseWeekday parses an English weekday name, or its first three letters
+func parseWeekday(s string) (time.Weekday, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		name := strings.ToLower(d.String())
+		if s == name || s == name[:3] {
+			return d, nil
+		}
+	}
+	return 0, fmt.Errorf("invalid weekday %q", s)
+}
+
+// parseTimeOfDay parses HH:MM as the time since midnight
+func parseTimeOfDay(s string) (time.Duration, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(s))
+	if err != nil {
+		return 0, fmt.Errorf("invalid time of day %q, expected HH:MM", s)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
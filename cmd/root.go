@@ -25,38 +25,97 @@ import (
 	"os"
 
 	"encoding/json"
+	"github.com/mandrean/go-trafikverket/pkg"
+	"github.com/mandrean/go-trafikverket/pkg/cassette"
+	"github.com/mandrean/go-trafikverket/pkg/store"
 	"github.com/mitchellh/go-homedir"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 	"gopkg.in/yaml.v2"
+	"strings"
 )
 
 var (
-	cfgFile string
-	Output  string
-	Debug   bool
+	cfgFile   string
+	profile   string
+	storePath string
+	recordDir string
+	replayDir string
+	Output    string
+	Debug     bool
+	NoRedact  bool
+	Strict    bool
+
+	errorFormat string
+
+	// configFileUsed is the config file read by initConfig, if any
+	configFileUsed string
+
+	// recordStore records occasion polls when --store is set
+	recordStore *store.Store
 )
 
 // RootCmd represents the base command when called without any subcommands
 var RootCmd = &cobra.Command{
 	Use:   "go-trafikverket",
 	Short: "A brief description of your application",
-	Long:  `A CLI tool for interacting with Trafikverket's Förarprov APIs`,
+	Long: `A CLI tool for interacting with Trafikverket's Förarprov APIs
+
+Every flag can also be set with an environment variable, prefixed with
+GO_TRAFIKVERKET_ and with dashes replaced by underscores, e.g.
+GO_TRAFIKVERKET_SOCIAL_SECURITY_NUMBER for --social-security-number.
+
+Exit codes:
+  0  success
+  1  other error
+  2  usage error, e.g. a missing flag or invalid social security number
+  3  authentication error, Trafikverket rejected the booking session
+  4  upstream error, Trafikverket failed or responded unexpectedly
+  5  no results`,
+
+	SilenceErrors: true,
+	SilenceUsage:  true,
 
-	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+	PersistentPreRunE: run(func(cmd *cobra.Command, args []string) error {
+		if err := applyConfig(cmd); err != nil {
+			return usageError("%w", err)
+		}
+		if errorFormat != "text" && errorFormat != "json" {
+			return usageError("--error-format must be text or json, got %q", errorFormat)
+		}
 		if Debug {
 			log.SetLevel(log.DebugLevel)
 		}
-	},
+		if configFileUsed != "" {
+			log.Debugln("Using config file:", configFileUsed)
+		}
+		if NoRedact {
+			pkg.DisableRedaction = true
+		}
+		if recordDir != "" && replayDir != "" {
+			return usageError("--record and --replay can't be used together")
+		}
+		if storePath != "" {
+			s, err := store.Open(storePath)
+			if err != nil {
+				return err
+			}
+			recordStore = s
+		}
+		return nil
+	}),
 }
 
 // Execute adds all child commands to the root command and sets flags appropriately.
 // This is called by main.main(). It only needs to happen once to the rootCmd.
 func Execute() {
-	if err := RootCmd.Execute(); err != nil {
-		fmt.Println(err)
-		os.Exit(1)
+	_, err := RootCmd.ExecuteC()
+	if recordStore != nil {
+		recordStore.Close()
+	}
+	if err != nil {
+		os.Exit(reportError(err))
 	}
 }
 
@@ -64,12 +123,24 @@ func init() {
 	cobra.OnInitialize(initConfig)
 
 	RootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.go-trafikverket.yaml)")
+	RootCmd.PersistentFlags().StringVarP(&profile, "profile", "P", "", "profile from the config file to use for unset flags")
 	RootCmd.PersistentFlags().StringVarP(&Output, "output", "o", "wide", "Output format. One of: json|yaml|wide. Default: wide")
 	RootCmd.PersistentFlags().BoolVarP(&Debug, "debug", "d", false, "debug output")
+	RootCmd.PersistentFlags().StringVar(&storePath, "store", "", "record every occasion poll in this history database")
+	RootCmd.PersistentFlags().StringVar(&recordDir, "record", "", "record all API requests and responses to this directory")
+	RootCmd.PersistentFlags().StringVar(&replayDir, "replay", "", "replay API responses recorded with --record from this directory instead of calling Trafikverket")
+	RootCmd.PersistentFlags().StringVar(&errorFormat, "error-format", "text", "Format of errors printed to stderr. One of: text|json")
+	RootCmd.PersistentFlags().BoolVar(&Strict, "strict", false, "fail when an API response has drifted from this tool, see doctor schema")
+	RootCmd.PersistentFlags().BoolVar(&NoRedact, "no-redact", false, "don't mask social security numbers in logs and output (local debugging only)")
 }
 
 // initConfig reads in config file and ENV variables if set.
 func initConfig() {
+	// The config file is needed before flags are filled in from the environment.
+	if cfgFile == "" {
+		cfgFile = os.Getenv(envName("config"))
+	}
+
 	if cfgFile != "" {
 		// Use config file from the flag.
 		viper.SetConfigFile(cfgFile)
@@ -86,12 +157,33 @@ func initConfig() {
 		viper.SetConfigName(".go-trafikverket")
 	}
 
+	viper.SetEnvPrefix(envPrefix)
+	viper.SetEnvKeyReplacer(strings.NewReplacer("-", "_"))
 	viper.AutomaticEnv() // read in environment variables that match
 
-	// If a config file is found, read it in.
+	// If a config file is found, read it in. It's logged once the log level is known.
 	if err := viper.ReadInConfig(); err == nil {
-		fmt.Println("Using config file:", viper.ConfigFileUsed())
+		configFileUsed = viper.ConfigFileUsed()
+	}
+}
+
+// newClient creates a client with the options set by the persistent flags, followed by opts
+func newClient(opts ...pkg.ClientOption) *pkg.TrafikverketClient {
+	// the cassette wraps the transport first, so other instrumentation sees replayed requests too
+	var base []pkg.ClientOption
+	if recordDir != "" {
+		base = append(base, cassette.New(recordDir, cassette.Record).ClientOption())
+	}
+	if replayDir != "" {
+		base = append(base, cassette.New(replayDir, cassette.Replay).ClientOption())
+	}
+	if recordStore != nil {
+		base = append(base, pkg.WithRecorder(recordStore))
+	}
+	if Strict {
+		base = append(base, pkg.WithStrictDecoding())
 	}
+	return pkg.NewClient(append(base, opts...)...)
 }
 
 // printJSON tries to print the data type as JSON
@@ -100,7 +192,7 @@ func printJSON(d interface{}) {
 	if err != nil {
 		log.Errorln(err)
 	}
-	fmt.Println(string(b))
+	fmt.Println(pkg.RedactString(string(b)))
 }
 
 // printYAML tries to print the data type as YAML
@@ -109,5 +201,5 @@ func printYAML(d interface{}) {
 	if err != nil {
 		log.Errorln(err)
 	}
-	fmt.Println(string(b))
+	fmt.Println(pkg.RedactString(string(b)))
 }
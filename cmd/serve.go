@@ -0,0 +1,90 @@
+// Copyright © 2017 Sebastian Mandrean <sebastian.mandrean@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"github.com/mandrean/go-trafikverket/pkg"
+	"github.com/mandrean/go-trafikverket/pkg/metrics"
+	"github.com/mandrean/go-trafikverket/pkg/server"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"net/http"
+	"time"
+)
+
+var (
+	listenAddress string
+	cacheTTL      time.Duration
+	rateInterval  time.Duration
+	withMetrics   bool
+)
+
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Serve licence categories, exam locations and exam occasions over a local REST API",
+	Long: `Serve licence categories, exam locations and exam occasions over a local REST/JSON API:
+
+  GET /licence-categories
+  GET /locations?ssn=...
+  GET /occasions?ssn=...&location=...
+  GET /openapi.json
+  GET /metrics (with --metrics)`,
+	RunE: run(serve),
+}
+
+func init() {
+	RootCmd.AddCommand(serveCmd)
+
+	serveCmd.Flags().StringVarP(&listenAddress, "listen-address", "a", "localhost:8080", "(Optional) Address to listen on")
+	serveCmd.Flags().DurationVar(&cacheTTL, "cache-ttl", time.Minute, "(Optional) How long to cache responses. 0 disables caching")
+	serveCmd.Flags().DurationVar(&rateInterval, "rate-interval", time.Second, "(Optional) Minimum interval between requests to Trafikverket. 0 disables rate limiting")
+	serveCmd.Flags().BoolVar(&withMetrics, "metrics", false, "(Optional) Expose Prometheus metrics on /metrics")
+}
+
+func serve(cmd *cobra.Command, args []string) error {
+	var opts []pkg.ClientOption
+	sopts := server.Options{
+		CacheTTL:     cacheTTL,
+		RateInterval: rateInterval,
+	}
+
+	// set up instrumentation
+	reg := prometheus.NewRegistry()
+	if withMetrics {
+		m := metrics.New(reg)
+		opts = append(opts, m.ClientOption())
+		sopts.OccasionsHook = m.ObserveOccasions
+	}
+
+	// create client
+	tc := newClient(opts...)
+
+	// create server
+	s := server.New(tc, sopts)
+	if withMetrics {
+		s.Handle("/metrics", promhttp.HandlerFor(reg, promhttp.HandlerOpts{}))
+	}
+
+	log.Infof("Listening on %v", listenAddress)
+	return http.ListenAndServe(listenAddress, s)
+}
@@ -0,0 +1,196 @@
+// Copyright © 2017 Sebastian Mandrean <sebastian.mandrean@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"github.com/gosuri/uitable"
+	"github.com/mandrean/go-trafikverket/pkg"
+	"github.com/mandrean/go-trafikverket/pkg/personnummer"
+	"github.com/spf13/cobra"
+	"strconv"
+)
+
+var weeks int
+
+var summaryCmd = &cobra.Command{
+	Use:   "summary",
+	Short: "Summarize exam occasion availability across locations and weeks",
+	Long: `Summarize exam occasion availability across locations and weeks, as a
+heatmap of the number of occasions per location per week and the earliest
+occasion at each location.`,
+	RunE: run(summary),
+}
+
+func init() {
+	RootCmd.AddCommand(summaryCmd)
+
+	summaryCmd.Flags().StringVarP(&socialSecurityNumber, "social-security-number", "S", "", "(Required) Social security number")
+	summaryCmd.Flags().IntVarP(&licenceID, "licence-id", "t", 0, "(Optional) License ID/type, defaults to Trafikverket's default")
+	summaryCmd.Flags().IntVarP(&bookingModeID, "booking-mode-id", "B", 0, "(Optional) Booking mode ID/type")
+	summaryCmd.Flags().BoolVarP(&ignoreDebt, "ignore-debt", "I", false, "(Optional) Ignore debt")
+
+	summaryCmd.Flags().IntSliceVarP(&locationIDs, "location-ids", "L", nil, "(Required) Comma-separated location IDs")
+	summaryCmd.Flags().IntVarP(&weeks, "weeks", "w", 8, "(Optional) Number of weeks to summarize, starting with the current week")
+	summaryCmd.Flags().IntVarP(&languageID, "language-id", "l", 0, "(Optional) Language ID, defaults to Trafikverket's default for the licence")
+	summaryCmd.Flags().IntVarP(&vehicleTypeID, "vehicle-type-id", "V", 0, "(Optional) Vehicle type ID, defaults to Trafikverket's default for the licence")
+	summaryCmd.Flags().IntVarP(&tachographTypeID, "tachograph-type-id", "T", 0, "(Optional) Tachograph type ID, defaults to Trafikverket's default for the licence")
+	summaryCmd.Flags().IntVarP(&occasionChoiceID, "occasion-choice-id", "O", 0, "(Optional) Occasion choice ID, defaults to Trafikverket's default for the licence")
+	summaryCmd.Flags().IntVarP(&examinationTypeID, "examination-type-id", "E", 0, "(Optional) Examination type ID")
+	summaryCmd.Flags().IntVarP(&parallelism, "parallelism", "p", 4, "(Optional) Number of locations to query concurrently")
+}
+
+func summary(cmd *cobra.Command, args []string) error {
+	// create client
+	tc := newClient()
+
+	// check required flags
+	var missing []string
+	if socialSecurityNumber == "" {
+		missing = append(missing, "--social-security-number/-S")
+	}
+	if len(locationIDs) == 0 {
+		missing = append(missing, "--location-ids/-L")
+	}
+	if err := required(missing); err != nil {
+		return err
+	}
+
+	// validate social security number
+	ssn, err := personnummer.Normalise(socialSecurityNumber)
+	if err != nil {
+		return err
+	}
+
+	// create payload template
+	body := &pkg.OccasionBundlesRequest{
+		BookingSession: pkg.BookingSession{
+			SocialSecurityNumber: ssn,
+			LicenceID:            licenceID,
+			BookingModeID:        bookingModeID,
+			IgnoreDebt:           ignoreDebt,
+			ExaminationTypeID:    examinationTypeID,
+		},
+		OccasionBundleQuery: pkg.OccasionBundleQuery{
+			LanguageID:        languageID,
+			VehicleTypeID:     vehicleTypeID,
+			TachographTypeID:  tachographTypeID,
+			OccasionChoiceID:  occasionChoiceID,
+			ExaminationTypeID: examinationTypeID,
+		},
+	}
+
+	// fill in Trafikverket's defaults, checking the query at the first location
+	body.OccasionBundleQuery.LocationID = locationIDs[0]
+	body, err = pkg.ResolveQuery(context.Background(), tc, body)
+	if err != nil {
+		return err
+	}
+
+	// summarize occasions
+	s := pkg.Summarize(context.Background(), tc, body, locationIDs, weeks, parallelism)
+
+	// print results
+	switch Output {
+	case "wide":
+		printSummaryWide(s)
+		break
+	case "json":
+		printJSON(s)
+		break
+	case "yaml":
+		printYAML(s)
+	default:
+		printSummaryWide(s)
+	}
+
+	// fail if any location did, and report no results only if all were summarized successfully
+	found := false
+	var errs []error
+	for _, l := range s.Locations {
+		found = found || l.Earliest != nil
+		if l.Err != nil {
+			errs = append(errs, l.Err)
+		}
+	}
+	switch {
+	case len(errs) > 0:
+		return fmt.Errorf("%d of %d locations failed, first: %w", len(errs), len(s.Locations), errs[0])
+	case !found:
+		return noResults("no occasions found at any location")
+	}
+	return nil
+}
+
+func printSummaryWide(s *pkg.Summary) {
+	table := uitable.New()
+	table.MaxColWidth = 50
+
+	// find the busiest week for scaling the heatmap
+	max := 0
+	for _, l := range s.Locations {
+		for _, c := range l.Counts {
+			if c > max {
+				max = c
+			}
+		}
+	}
+
+	header := []interface{}{"LOCATION"}
+	for _, w := range s.Weeks {
+		_, n := w.ISOWeek()
+		header = append(header, "W"+strconv.Itoa(n))
+	}
+	header = append(header, "EARLIEST")
+	table.AddRow(header...)
+
+	for _, l := range s.Locations {
+		name := l.LocationName
+		if name == "" {
+			name = strconv.Itoa(l.LocationID)
+		}
+
+		row := []interface{}{name}
+		for _, c := range l.Counts {
+			row = append(row, fmt.Sprintf("%v %3d", shade(c, max), c))
+		}
+		switch {
+		case l.Error != "":
+			row = append(row, l.Error)
+		case l.Earliest != nil:
+			row = append(row, l.Earliest.Date+" "+l.Earliest.Time)
+		default:
+			row = append(row, "-")
+		}
+		table.AddRow(row...)
+	}
+	fmt.Println(table)
+}
+
+// shade renders count relative to max as a block character
+func shade(count int, max int) string {
+	shades := []string{" ", "░", "▒", "▓", "█"}
+	if count <= 0 || max <= 0 {
+		return shades[0]
+	}
+	return shades[(count*(len(shades)-1)+max-1)/max]
+}
@@ -0,0 +1,167 @@
+// Copyright © 2017 Sebastian Mandrean <sebastian.mandrean@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package analytics computes slot release patterns from recorded occasion history.
+package analytics
+
+import (
+	"github.com/mandrean/go-trafikverket/pkg/store"
+	"sort"
+	"time"
+)
+
+const (
+	// bestPollingHours is the number of hours reported in Report.BestPollingHours
+	bestPollingHours = 3
+	// bestPollingDays is the number of days reported in Report.BestPollingDays
+	bestPollingDays = 2
+)
+
+type (
+	// Report describes how occasions are released at a location
+	Report struct {
+		LocationID   int    `yaml:"locationId"`
+		LocationName string `yaml:"locationName"`
+		// Released is the number of occasions observed appearing for the first time
+		Released int `yaml:"released"`
+		// Cancellations is the number of occasions that reappeared after having been taken
+		Cancellations int `yaml:"cancellations"`
+		// LeadTime is the time from an occasion being released to it starting
+		LeadTime Stats `yaml:"leadTime"`
+		// Availability is how long a released occasion stayed available before being taken
+		Availability Stats `yaml:"availability"`
+		// ReleaseHours counts released and reappearing occasions by hour of day
+		ReleaseHours [24]int `yaml:"releaseHours"`
+		// BestPollingHours are the hours of day when the most occasions appear, busiest first
+		BestPollingHours []int `yaml:"bestPollingHours"`
+		// ReleaseWeekdays counts released and reappearing occasions by day of week, indexed by time.Weekday
+		ReleaseWeekdays [7]int `yaml:"releaseWeekdays"`
+		// BestPollingDays are the days of week when the most occasions appear, busiest first
+		BestPollingDays []time.Weekday `yaml:"bestPollingDays"`
+	}
+
+	// Stats summarizes a set of durations
+	Stats struct {
+		Count  int           `yaml:"count"`
+		Mean   time.Duration `yaml:"mean"`
+		Median time.Duration `yaml:"median"`
+		Min    time.Duration `yaml:"min"`
+		Max    time.Duration `yaml:"max"`
+	}
+)
+
+// Analyze computes a report from the snapshots of a single location, oldest first.
+// Hours and days of week are in loc.
+func Analyze(locationID int, snaps []store.Snapshot, loc *time.Location) Report {
+	r := Report{LocationID: locationID}
+	for _, s := range snaps {
+		for _, d := range s.Response.Data {
+			for _, o := range d.Occasions {
+				if o.LocationName != "" {
+					r.LocationName = o.LocationName
+				}
+			}
+		}
+	}
+
+	changes := store.Changes(snaps)
+	r.ReleaseHours = store.ReleaseHours(changes, loc)
+	r.BestPollingHours = busiest(r.ReleaseHours[:], bestPollingHours)
+	for _, c := range changes {
+		if c.Kind == store.Appeared {
+			r.ReleaseWeekdays[c.Time.In(loc).Weekday()]++
+		}
+	}
+	for _, d := range busiest(r.ReleaseWeekdays[:], bestPollingDays) {
+		r.BestPollingDays = append(r.BestPollingDays, time.Weekday(d))
+	}
+
+	// follow each occasion through its appearances and disappearances
+	var leadTimes, availability []time.Duration
+	available := make(map[string]time.Time)
+	taken := make(map[string]bool)
+	for _, c := range changes {
+		k := c.Occasion.Key()
+		switch c.Kind {
+		case store.Appeared:
+			if taken[k] {
+				r.Cancellations++
+			} else {
+				r.Released++
+				leadTimes = append(leadTimes, c.Occasion.Duration.Start.Sub(c.Time))
+			}
+			available[k] = c.Time
+		case store.Disappeared:
+			// occasions already available in the first snapshot have an unknown release time
+			if t, ok := available[k]; ok {
+				availability = append(availability, c.Time.Sub(t))
+				delete(available, k)
+			}
+			taken[k] = true
+		}
+	}
+
+	r.LeadTime = summarize(leadTimes)
+	r.Availability = summarize(availability)
+
+	return r
+}
+
+// summarize computes statistics for ds
+func summarize(ds []time.Duration) Stats {
+	if len(ds) == 0 {
+		return Stats{}
+	}
+
+	sort.Slice(ds, func(i, j int) bool { return ds[i] < ds[j] })
+
+	var sum time.Duration
+	for _, d := range ds {
+		sum += d
+	}
+
+	median := ds[len(ds)/2]
+	if len(ds)%2 == 0 {
+		median = (ds[len(ds)/2-1] + ds[len(ds)/2]) / 2
+	}
+
+	return Stats{
+		Count:  len(ds),
+		Mean:   sum / time.Duration(len(ds)),
+		Median: median,
+		Min:    ds[0],
+		Max:    ds[len(ds)-1],
+	}
+}
+
+// busiest returns the indices of up to n of counts with the most releases, busiest first
+func busiest(counts []int, n int) []int {
+	var hs []int
+	for h, c := range counts {
+		if c > 0 {
+			hs = append(hs, h)
+		}
+	}
+	sort.SliceStable(hs, func(i, j int) bool { return counts[hs[i]] > counts[hs[j]] })
+	if len(hs) > n {
+		hs = hs[:n]
+	}
+	return hs
+}
@@ -0,0 +1,115 @@
+// Copyright © 2017 Sebastian Mandrean <sebastian.mandrean@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package analytics_test
+
+import (
+	"github.com/mandrean/go-trafikverket/pkg"
+	"github.com/mandrean/go-trafikverket/pkg/analytics"
+	"github.com/mandrean/go-trafikverket/pkg/store"
+	"reflect"
+	"testing"
+	"time"
+)
+
+const stockholm = 1000140
+
+// monday is a Monday morning in UTC
+var monday = time.Date(2030, 6, 3, 9, 0, 0, 0, time.UTC)
+
+// snapshot returns a poll at t of the occasions starting at starts
+func snapshot(t time.Time, starts ...time.Time) store.Snapshot {
+	snap := store.Snapshot{Time: t, LicenceID: pkg.DefaultLicenceID, Query: pkg.OccasionBundleQuery{LocationID: stockholm}}
+	for _, s := range starts {
+		o := pkg.Occasion{LocationID: stockholm, LocationName: "Stockholm", ExaminationTypeID: 12}
+		o.Duration.Start = s
+		snap.Response.Data = append(snap.Response.Data, pkg.Bundle{Occasions: []pkg.Occasion{o}})
+	}
+	return snap
+}
+
+func TestAnalyze(t *testing.T) {
+	// b is released late on Monday, taken on Tuesday and cancelled early on Wednesday, when c is released.
+	// z is available throughout, so its release time is unknown.
+	b, c, z := monday.AddDate(0, 0, 3), monday.AddDate(0, 0, 4), monday.AddDate(0, 0, 10)
+	released, taken := monday.Add(14*time.Hour+30*time.Minute), monday.AddDate(0, 0, 1).Add(3*time.Hour)
+	cancelled := monday.AddDate(0, 0, 2).Add(-3 * time.Hour)
+	history := []store.Snapshot{
+		snapshot(monday, z),
+		snapshot(released, b, z),
+		snapshot(taken, z),
+		snapshot(cancelled, b, z),
+		snapshot(cancelled.Add(30*time.Minute), b, c, z),
+	}
+	leadTimes := []time.Duration{c.Sub(cancelled.Add(30 * time.Minute)), b.Sub(released)}
+
+	tests := []struct {
+		name     string
+		snaps    []store.Snapshot
+		loc      *time.Location
+		hours    map[int]int
+		weekdays map[time.Weekday]int
+		want     analytics.Report
+	}{
+		{"empty history", nil, time.UTC, nil, nil, analytics.Report{LocationID: stockholm}},
+		{"single poll", history[:1], time.UTC, nil, nil, analytics.Report{LocationID: stockholm, LocationName: "Stockholm"}},
+		{"utc", history, time.UTC,
+			map[int]int{23: 1, 6: 2},
+			map[time.Weekday]int{time.Monday: 1, time.Wednesday: 2},
+			analytics.Report{
+				BestPollingHours: []int{6, 23},
+				BestPollingDays:  []time.Weekday{time.Wednesday, time.Monday},
+			},
+		},
+		{"local time", history, time.FixedZone("CEST", 2*60*60),
+			map[int]int{1: 1, 8: 2},
+			map[time.Weekday]int{time.Tuesday: 1, time.Wednesday: 2},
+			analytics.Report{
+				BestPollingHours: []int{8, 1},
+				BestPollingDays:  []time.Weekday{time.Wednesday, time.Tuesday},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want := tt.want
+			want.LocationID = stockholm
+			if tt.hours != nil {
+				want.LocationName = "Stockholm"
+				want.Released = 2
+				want.Cancellations = 1
+				want.LeadTime = analytics.Stats{Count: 2, Mean: (leadTimes[0] + leadTimes[1]) / 2, Median: (leadTimes[0] + leadTimes[1]) / 2, Min: leadTimes[0], Max: leadTimes[1]}
+				want.Availability = analytics.Stats{Count: 1, Mean: taken.Sub(released), Median: taken.Sub(released), Min: taken.Sub(released), Max: taken.Sub(released)}
+			}
+			for h, n := range tt.hours {
+				want.ReleaseHours[h] = n
+			}
+			for d, n := range tt.weekdays {
+				want.ReleaseWeekdays[d] = n
+			}
+
+			got := analytics.Analyze(stockholm, tt.snaps, tt.loc)
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("got %+v, want %+v", got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,267 @@
+// Copyright © 2017 Sebastian Mandrean <sebastian.mandrean@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package pkg
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"gopkg.in/yaml.v2"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+type (
+	// Candidate is a student in a batch roster
+	Candidate struct {
+		Name                 string    `yaml:"name"`
+		SocialSecurityNumber string    `yaml:"socialSecurityNumber"`
+		LicenceID            int       `yaml:"licenceId"`
+		LocationIDs          []int     `yaml:"locationIds"`
+		LanguageID           int       `yaml:"languageId"`
+		ExaminationTypeID    int       `yaml:"examinationTypeId"`
+		Deadline             time.Time `yaml:"deadline"`
+	}
+
+	// BatchResult holds the occasions found for a candidate
+	BatchResult struct {
+		Candidate Candidate  `yaml:"candidate"`
+		Earliest  *Occasion  `yaml:"earliest"`
+		Occasions []Occasion `yaml:"occasions"`
+		Error     string     `yaml:"error,omitempty"`
+		// Err is the error Error describes, for telling failures apart
+		Err error `yaml:"-" json:"-"`
+	}
+)
+
+// LoadRoster reads a roster from a .csv, .yaml or .yml file
+func LoadRoster(path string) ([]Candidate, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".csv":
+		return ReadRosterCSV(f)
+	case ".yaml", ".yml":
+		return ReadRosterYAML(f)
+	default:
+		return nil, fmt.Errorf("unsupported roster format %q, expected .csv, .yaml or .yml", filepath.Ext(path))
+	}
+}
+
+// ReadRosterYAML reads a roster from a YAML list of candidates
+func ReadRosterYAML(r io.Reader) ([]Candidate, error) {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var cs []Candidate
+	if err := yaml.Unmarshal(b, &cs); err != nil {
+		return nil, err
+	}
+
+	return cs, nil
+}
+
+// ReadRosterCSV reads a roster from CSV with a header row using the same column names as the YAML keys.
+// locationIds are separated by semicolons and deadline is formatted as YYYY-MM-DD.
+func ReadRosterCSV(r io.Reader) ([]Candidate, error) {
+	records, err := csv.NewReader(r).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, nil
+	}
+
+	// map header names to column indices
+	cols := make(map[string]int)
+	for i, h := range records[0] {
+		cols[strings.ToLower(strings.TrimSpace(h))] = i
+	}
+	get := func(rec []string, name string) string {
+		if i, ok := cols[strings.ToLower(name)]; ok && i < len(rec) {
+			return strings.TrimSpace(rec[i])
+		}
+		return ""
+	}
+
+	var cs []Candidate
+	for n, rec := range records[1:] {
+		line := n + 2
+		c := Candidate{
+			Name:                 get(rec, "name"),
+			SocialSecurityNumber: get(rec, "socialSecurityNumber"),
+		}
+
+		for _, f := range []struct {
+			name string
+			v    *int
+		}{
+			{"licenceId", &c.LicenceID},
+			{"languageId", &c.LanguageID},
+			{"examinationTypeId", &c.ExaminationTypeID},
+		} {
+			if s := get(rec, f.name); s != "" {
+				if *f.v, err = strconv.Atoi(s); err != nil {
+					return nil, fmt.Errorf("line %d: invalid %v %q", line, f.name, s)
+				}
+			}
+		}
+
+		for _, s := range strings.Split(get(rec, "locationIds"), ";") {
+			if s = strings.TrimSpace(s); s == "" {
+				continue
+			}
+			id, err := strconv.Atoi(s)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: invalid location ID %q", line, s)
+			}
+			c.LocationIDs = append(c.LocationIDs, id)
+		}
+
+		if s := get(rec, "deadline"); s != "" {
+			if c.Deadline, err = time.Parse("2006-01-02", s); err != nil {
+				return nil, fmt.Errorf("line %d: invalid deadline %q", line, s)
+			}
+		}
+
+		cs = append(cs, c)
+	}
+
+	return cs, nil
+}
+
+// Batch searches occasions for every candidate in the roster, running at most parallelism searches at a time.
+// Results are returned in roster order, with per-candidate failures reported in BatchResult.Err and Error.
+// Candidates still waiting for their turn when ctx is done fail with its error.
+func Batch(ctx context.Context, client Client, roster []Candidate, parallelism int) []BatchResult {
+	ctx, span := tracerFor(client).Start(ctx, "Batch", trace.WithAttributes(
+		attribute.Int("trafikverket.candidates", len(roster)),
+		attribute.Int("trafikverket.parallelism", parallelism),
+	))
+	defer span.End()
+
+	if parallelism < 1 {
+		parallelism = 1
+	}
+
+	results := make([]BatchResult, len(roster))
+	sem := make(chan struct{}, parallelism)
+	var wg sync.WaitGroup
+	for i, c := range roster {
+		wg.Add(1)
+		go func(i int, c Candidate) {
+			defer wg.Done()
+			select {
+			case sem <- struct{}{}:
+				defer func() { <-sem }()
+			case <-ctx.Done():
+				results[i] = BatchResult{Candidate: c, Error: ctx.Err().Error(), Err: ctx.Err()}
+				return
+			}
+
+			results[i] = searchCandidate(ctx, client, c)
+		}(i, c)
+	}
+	wg.Wait()
+
+	return results
+}
+
+// searchCandidate finds the occasions before the candidate's deadline at all their preferred locations
+func searchCandidate(ctx context.Context, client Client, c Candidate) BatchResult {
+	ctx, span := tracerFor(client).Start(ctx, "BatchCandidate", trace.WithAttributes(
+		attribute.IntSlice("trafikverket.location_ids", c.LocationIDs),
+	))
+	defer span.End()
+
+	result := BatchResult{Candidate: c}
+	fail := func(err error) BatchResult {
+		result.Err = endSpan(span, err)
+		result.Error = result.Err.Error()
+		return result
+	}
+
+	if len(c.LocationIDs) == 0 {
+		return fail(errors.New("no locations specified"))
+	}
+
+	// resolve Trafikverket's defaults for the candidate's licence once and check every preferred location
+	// before searching any of them
+	resolved, info, err := resolveQuery(ctx, client, &OccasionBundlesRequest{
+		BookingSession: BookingSession{
+			SocialSecurityNumber: c.SocialSecurityNumber,
+			LicenceID:            c.LicenceID,
+			ExaminationTypeID:    c.ExaminationTypeID,
+		},
+		OccasionBundleQuery: OccasionBundleQuery{
+			LanguageID:        c.LanguageID,
+			ExaminationTypeID: c.ExaminationTypeID,
+		},
+	})
+	if err != nil {
+		return fail(err)
+	}
+	bodies := make([]OccasionBundlesRequest, len(c.LocationIDs))
+	for i, l := range c.LocationIDs {
+		bodies[i] = *resolved
+		bodies[i].OccasionBundleQuery.LocationID = l
+		if err := CheckQuery(info, bodies[i].OccasionBundleQuery); err != nil {
+			return fail(err)
+		}
+	}
+	for i := range bodies {
+		occasions, _, err := client.OccasionsContext(ctx, &bodies[i])
+		if err != nil {
+			return fail(err)
+		}
+
+		for _, o := range *occasions {
+			// the deadline is inclusive
+			if c.Deadline.IsZero() || o.Duration.Start.Before(c.Deadline.AddDate(0, 0, 1)) {
+				result.Occasions = append(result.Occasions, o)
+			}
+		}
+	}
+
+	sort.Slice(result.Occasions, func(i, j int) bool {
+		return result.Occasions[i].Duration.Start.Before(result.Occasions[j].Duration.Start)
+	})
+	if len(result.Occasions) > 0 {
+		result.Earliest = &result.Occasions[0]
+	}
+
+	return result
+}
@@ -0,0 +1,193 @@
+// Copyright © 2017 Sebastian Mandrean <sebastian.mandrean@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package pkg_test
+
+import (
+	"context"
+	"errors"
+	"github.com/mandrean/go-trafikverket/pkg"
+	"github.com/mandrean/go-trafikverket/pkg/trafikverkettest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReadRosterCSV(t *testing.T) {
+	header := "name,socialSecurityNumber,licenceId,locationIds,languageId,examinationTypeId,deadline\n"
+
+	tests := []struct {
+		name string
+		csv  string
+		want []pkg.Candidate
+		err  string
+	}{
+		{"valid", header + "Alice,199001010017,5,1000140;1000071,13,12,2030-06-01\nBob,199001010018,,1000072,,,\n", []pkg.Candidate{
+			{Name: "Alice", SocialSecurityNumber: "199001010017", LicenceID: 5, LocationIDs: []int{1000140, 1000071}, LanguageID: 13, ExaminationTypeID: 12, Deadline: time.Date(2030, 6, 1, 0, 0, 0, 0, time.UTC)},
+			{Name: "Bob", SocialSecurityNumber: "199001010018", LocationIDs: []int{1000072}},
+		}, ""},
+		{"header only", header, nil, ""},
+		{"empty", "", nil, ""},
+		{"invalid licence", header + "Alice,199001010017,B,1000140,,,\n", nil, `line 2: invalid licenceId "B"`},
+		{"invalid location", header + "Alice,199001010017,5,1000140;Solna,,,\n", nil, `line 2: invalid location ID "Solna"`},
+		{"invalid deadline", header + "Alice,199001010017,5,1000140,,,1 June\n", nil, `line 2: invalid deadline "1 June"`},
+		{"wrong number of fields", header + "Alice,199001010017\n", nil, "wrong number of fields"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := pkg.ReadRosterCSV(strings.NewReader(tt.csv))
+			checkRoster(t, got, err, tt.want, tt.err)
+		})
+	}
+}
+
+func TestReadRosterYAML(t *testing.T) {
+	tests := []struct {
+		name string
+		yaml string
+		want []pkg.Candidate
+		err  string
+	}{
+		{"valid", "- name: Alice\n  socialSecurityNumber: \"199001010017\"\n  locationIds: [1000140, 1000071]\n  languageId: 13\n", []pkg.Candidate{
+			{Name: "Alice", SocialSecurityNumber: "199001010017", LocationIDs: []int{1000140, 1000071}, LanguageID: 13},
+		}, ""},
+		{"empty", "", nil, ""},
+		{"not a list", "name: Alice\n", nil, "cannot unmarshal"},
+		{"invalid location", "- name: Alice\n  locationIds: [Solna]\n", nil, "cannot unmarshal"},
+		{"invalid syntax", "- name: [Alice\n", nil, "yaml:"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := pkg.ReadRosterYAML(strings.NewReader(tt.yaml))
+			checkRoster(t, got, err, tt.want, tt.err)
+		})
+	}
+}
+
+func checkRoster(t *testing.T, got []pkg.Candidate, err error, want []pkg.Candidate, wantErr string) {
+	t.Helper()
+	if wantErr != "" {
+		if err == nil || !strings.Contains(err.Error(), wantErr) {
+			t.Fatalf("got error %v, want it to contain %q", err, wantErr)
+		}
+		return
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d candidates, want %d: %+v", len(got), len(want), got)
+	}
+	for i := range want {
+		g, w := got[i], want[i]
+		if g.Name != w.Name || g.SocialSecurityNumber != w.SocialSecurityNumber || g.LicenceID != w.LicenceID ||
+			g.LanguageID != w.LanguageID || g.ExaminationTypeID != w.ExaminationTypeID || !g.Deadline.Equal(w.Deadline) ||
+			!equalInts(g.LocationIDs, w.LocationIDs) {
+			t.Errorf("candidate %d: got %+v, want %+v", i, g, w)
+		}
+	}
+}
+
+func TestBatch(t *testing.T) {
+	now := time.Now()
+	f := trafikverkettest.DefaultFixtures(now)
+	s := trafikverkettest.NewServer(f)
+	defer s.Close()
+
+	deadline := time.Date(now.Year(), now.Month(), now.Day()+7, 0, 0, 0, 0, time.UTC)
+	roster := []pkg.Candidate{
+		{Name: "all locations", SocialSecurityNumber: testSSN, LocationIDs: []int{1000140, 1000071, 1000072}, ExaminationTypeID: trafikverkettest.DrivingExaminationTypeID},
+		{Name: "no locations", SocialSecurityNumber: testSSN},
+		{Name: "unknown location", SocialSecurityNumber: testSSN, LocationIDs: []int{1000140, 99}},
+		{Name: "deadline", SocialSecurityNumber: testSSN, LocationIDs: []int{1000140}, ExaminationTypeID: trafikverkettest.DrivingExaminationTypeID, Deadline: deadline},
+	}
+
+	results := pkg.Batch(context.Background(), s.Client(), roster, 2)
+	if len(results) != len(roster) {
+		t.Fatalf("got %d results, want %d", len(results), len(roster))
+	}
+	for i, r := range results {
+		if r.Candidate.Name != roster[i].Name {
+			t.Errorf("result %d: got candidate %q, want %q in roster order", i, r.Candidate.Name, roster[i].Name)
+		}
+		if (r.Err == nil) != (r.Error == "") {
+			t.Errorf("result %d: got Err %v and Error %q", i, r.Err, r.Error)
+		}
+	}
+
+	// the failures don't affect the other candidates
+	all := results[0]
+	if all.Err != nil {
+		t.Fatal(all.Err)
+	}
+	locations := make(map[int]bool)
+	for _, o := range all.Occasions {
+		locations[o.LocationID] = true
+	}
+	if len(locations) != 3 {
+		t.Errorf("got occasions at %v, want all three locations", locations)
+	}
+	if all.Earliest == nil || !all.Earliest.Duration.Start.Equal(all.Occasions[0].Duration.Start) {
+		t.Errorf("got earliest %+v, want the first occasion", all.Earliest)
+	}
+
+	if results[1].Error != "no locations specified" {
+		t.Errorf("got error %q, want no locations specified", results[1].Error)
+	}
+	var ve *pkg.ValidationError
+	if !errors.As(results[2].Err, &ve) || len(results[2].Occasions) != 0 {
+		t.Errorf("got error %v and %d occasions, want a *ValidationError and none", results[2].Err, len(results[2].Occasions))
+	}
+
+	last := results[3]
+	if last.Err != nil {
+		t.Fatal(last.Err)
+	}
+	if len(last.Occasions) == 0 {
+		t.Fatal("got no occasions before the deadline")
+	}
+	for _, o := range last.Occasions {
+		if !o.Duration.Start.Before(deadline.AddDate(0, 0, 1)) {
+			t.Errorf("got occasion at %v, after the deadline %v", o.Duration.Start, deadline)
+		}
+	}
+
+	// the defaults are resolved once per candidate with locations, not once per location
+	if n := s.Requests("search-information"); n != 3 {
+		t.Errorf("got %d search-information requests, want 3", n)
+	}
+}
+
+func TestBatchCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	s := trafikverkettest.NewServer(trafikverkettest.DefaultFixtures(time.Now()))
+	defer s.Close()
+
+	roster := []pkg.Candidate{{SocialSecurityNumber: testSSN, LocationIDs: []int{stockholm}}, {SocialSecurityNumber: testSSN, LocationIDs: []int{stockholm}}}
+	for _, r := range pkg.Batch(ctx, s.Client(), roster, 1) {
+		if !errors.Is(r.Err, context.Canceled) {
+			t.Errorf("got error %v, want %v", r.Err, context.Canceled)
+		}
+	}
+}
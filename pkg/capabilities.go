@@ -0,0 +1,151 @@
+// Copyright © 2017 Sebastian Mandrean <sebastian.mandrean@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package pkg
+
+import (
+	"context"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"sort"
+)
+
+type (
+	// Capability is a combination of examination type, language and vehicle type offered at a location
+	Capability struct {
+		LocationID          int    `yaml:"locationId"`
+		LocationName        string `yaml:"locationName"`
+		ExaminationTypeID   int    `yaml:"examinationTypeId"`
+		ExaminationTypeName string `yaml:"examinationTypeName"`
+		LanguageID          int    `yaml:"languageId"`
+		LanguageName        string `yaml:"languageName"`
+		VehicleTypeID       int    `yaml:"vehicleTypeId"`
+		VehicleTypeName     string `yaml:"vehicleTypeName"`
+	}
+
+	// CapabilityFilter selects capabilities. Zero fields match anything.
+	CapabilityFilter struct {
+		LocationID        int `yaml:"locationId"`
+		ExaminationTypeID int `yaml:"examinationTypeId"`
+		LanguageID        int `yaml:"languageId"`
+		VehicleTypeID     int `yaml:"vehicleTypeId"`
+	}
+)
+
+// Match reports whether c is selected by f
+func (f CapabilityFilter) Match(c Capability) bool {
+	return (f.LocationID == 0 || f.LocationID == c.LocationID) &&
+		(f.ExaminationTypeID == 0 || f.ExaminationTypeID == c.ExaminationTypeID) &&
+		(f.LanguageID == 0 || f.LanguageID == c.LanguageID) &&
+		(f.VehicleTypeID == 0 || f.VehicleTypeID == c.VehicleTypeID)
+}
+
+// FilterCapabilities returns the capabilities selected by f
+func FilterCapabilities(cs []Capability, f CapabilityFilter) []Capability {
+	var filtered []Capability
+	for _, c := range cs {
+		if f.Match(c) {
+			filtered = append(filtered, c)
+		}
+	}
+	return filtered
+}
+
+// Capabilities cross-references the languages, vehicle types and examination types of the licence in bs
+// against its locations, by searching information for each examination type. A language without location IDs
+// is offered at every location. Trafikverket does not tell which vehicle types each location offers, so
+// every vehicle type of an examination type is assumed to be offered wherever the examination type is.
+func Capabilities(ctx context.Context, client Client, bs BookingSession) ([]Capability, error) {
+	ctx, span := tracerFor(client).Start(ctx, "Capabilities", trace.WithAttributes(
+		attribute.Int("trafikverket.licence_id", bs.LicenceID),
+	))
+	defer span.End()
+
+	info, _, err := client.SearchInformationContext(ctx, &SearchInformationRequest{BookingSession: bs})
+	if err != nil {
+		return nil, endSpan(span, err)
+	}
+
+	var cs []Capability
+	if len(info.Data.ExaminationTypes) == 0 {
+		cs = capabilities(info, ExaminationType{})
+	}
+	for _, e := range info.Data.ExaminationTypes {
+		// locations and languages depend on the examination type
+		bs.ExaminationTypeID = int(e.ID)
+		info, _, err := client.SearchInformationContext(ctx, &SearchInformationRequest{BookingSession: bs})
+		if err != nil {
+			return nil, endSpan(span, err)
+		}
+		cs = append(cs, capabilities(info, e)...)
+	}
+
+	sort.SliceStable(cs, func(i, j int) bool {
+		if cs[i].LocationName != cs[j].LocationName {
+			return cs[i].LocationName < cs[j].LocationName
+		}
+		return cs[i].ExaminationTypeID < cs[j].ExaminationTypeID
+	})
+	span.SetAttributes(attribute.Int("trafikverket.results", len(cs)))
+
+	return cs, nil
+}
+
+// capabilities lists the capabilities of the examination type in info
+func capabilities(info *SearchInformationResponse, e ExaminationType) []Capability {
+	var cs []Capability
+	for _, l := range info.Data.Locations {
+		for _, lang := range info.Data.Languages {
+			if !offers(lang, int(l.ID)) {
+				continue
+			}
+			vehicleTypes := info.Data.VehicleTypes
+			if len(vehicleTypes) == 0 {
+				vehicleTypes = []VehicleType{{}}
+			}
+			for _, v := range vehicleTypes {
+				cs = append(cs, Capability{
+					LocationID:          int(l.ID),
+					LocationName:        l.Name,
+					ExaminationTypeID:   int(e.ID),
+					ExaminationTypeName: e.Name,
+					LanguageID:          int(lang.ID),
+					LanguageName:        lang.Name,
+					VehicleTypeID:       v.ID,
+					VehicleTypeName:     v.Name,
+				})
+			}
+		}
+	}
+	return cs
+}
+
+// offers reports whether the language is offered at the location
+func offers(lang Language, locationID int) bool {
+	if len(lang.LocationIDs) == 0 {
+		return true
+	}
+	for _, id := range lang.LocationIDs {
+		if id == locationID {
+			return true
+		}
+	}
+	return false
+}
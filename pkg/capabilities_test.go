@@ -0,0 +1,66 @@
+// Copyright © 2017 Sebastian Mandrean <sebastian.mandrean@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package pkg_test
+
+import (
+	"context"
+	"github.com/mandrean/go-trafikverket/pkg"
+	"github.com/mandrean/go-trafikverket/pkg/trafikverkettest"
+	"testing"
+	"time"
+)
+
+func TestCapabilities(t *testing.T) {
+	bs := pkg.BookingSession{SocialSecurityNumber: testSSN, LicenceID: pkg.DefaultLicenceID}
+
+	tests := []struct {
+		name   string
+		filter pkg.CapabilityFilter
+		want   []int
+	}{
+		{"theory test in English with a manual car", pkg.CapabilityFilter{ExaminationTypeID: trafikverkettest.TheoryExaminationTypeID, LanguageID: 4, VehicleTypeID: 1}, []int{stockholm}},
+		{"driving test in Swedish with an automatic car", pkg.CapabilityFilter{ExaminationTypeID: trafikverkettest.DrivingExaminationTypeID, LanguageID: 13, VehicleTypeID: 4}, []int{1000071, 1000072, 1000140}},
+		{"unknown vehicle type", pkg.CapabilityFilter{VehicleTypeID: 99}, nil},
+	}
+
+	for name, c := range fakes(t, trafikverkettest.DefaultFixtures(time.Now())) {
+		t.Run(name, func(t *testing.T) {
+			cs, err := pkg.Capabilities(context.Background(), c, bs)
+			if err != nil {
+				t.Fatal(err)
+			}
+			// 2 examination types at 3 locations in Swedish, 1 in English, with 2 vehicle types
+			if len(cs) != 2*(3+1)*2 {
+				t.Errorf("got %d capabilities, want 16", len(cs))
+			}
+
+			for _, tt := range tests {
+				var got []int
+				for _, c := range pkg.FilterCapabilities(cs, tt.filter) {
+					got = append(got, c.LocationID)
+				}
+				if !equalInts(got, tt.want) {
+					t.Errorf("%v: got locations %v, want %v", tt.name, got, tt.want)
+				}
+			}
+		})
+	}
+}
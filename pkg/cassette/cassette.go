@@ -0,0 +1,257 @@
+// Copyright © 2017 Sebastian Mandrean <sebastian.mandrean@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package cassette records and replays HTTP interactions with the Förarprov API, for deterministic
+// tests and demos without hitting Trafikverket. Personnummer are masked in everything stored.
+package cassette
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/mandrean/go-trafikverket/pkg"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+const (
+	// Record performs real requests and saves them
+	Record Mode = iota
+	// Replay serves saved requests, failing on requests that weren't recorded
+	Replay
+)
+
+// ignoredFields are left out when matching requests, as they are derived from the time of the request
+var ignoredFields = []string{"startDate"}
+
+type (
+	// Mode is whether a Cassette records or replays
+	Mode int
+
+	// Cassette records interactions to, or replays them from, a directory
+	Cassette struct {
+		dir  string
+		mode Mode
+
+		mu sync.Mutex
+		// seq counts the requests seen per key, so repeated requests are replayed in the order recorded
+		seq map[string]int
+	}
+
+	// Interaction is a recorded request/response pair
+	Interaction struct {
+		Request  Request  `yaml:"request"`
+		Response Response `yaml:"response"`
+	}
+
+	// Request is a recorded request
+	Request struct {
+		Method string `yaml:"method"`
+		URL    string `yaml:"url"`
+		Body   string `yaml:"body"`
+	}
+
+	// Response is a recorded response
+	Response struct {
+		StatusCode int         `yaml:"statusCode"`
+		Status     string      `yaml:"status"`
+		Header     http.Header `yaml:"header"`
+		Body       string      `yaml:"body"`
+	}
+
+	roundTripper struct {
+		c    *Cassette
+		next http.RoundTripper
+	}
+)
+
+// New creates a Cassette for dir
+func New(dir string, mode Mode) *Cassette {
+	return &Cassette{dir: dir, mode: mode, seq: make(map[string]int)}
+}
+
+// ClientOption makes a TrafikverketClient use c, for use with pkg.NewClient
+func (c *Cassette) ClientOption() pkg.ClientOption {
+	return pkg.WithRoundTripper(c.RoundTripper)
+}
+
+// RoundTripper wraps next, recording or replaying its interactions
+func (c *Cassette) RoundTripper(next http.RoundTripper) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &roundTripper{c: c, next: next}
+}
+
+func (rt *roundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	// read and sanitize the request body
+	var body []byte
+	if req.Body != nil {
+		b, err := io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		body = b
+		req.Body = io.NopCloser(bytes.NewReader(b))
+	}
+	r := Request{
+		Method: req.Method,
+		URL:    req.URL.String(),
+		Body:   pkg.MaskPersonnummer(string(body)),
+	}
+	name, n := rt.c.next(r, req.URL.Path)
+	file := filepath.Join(rt.c.dir, name)
+
+	if rt.c.mode == Replay {
+		i, err := load(file)
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("cassette: no recorded interaction for %v %v #%d in %v", r.Method, req.URL.Path, n+1, rt.c.dir)
+		}
+		if err != nil {
+			return nil, err
+		}
+		return i.Response.response(req), nil
+	}
+
+	// record
+	res, err := rt.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	b, err := io.ReadAll(res.Body)
+	res.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	res.Body = io.NopCloser(bytes.NewReader(b))
+
+	// don't persist session cookies
+	h := res.Header.Clone()
+	h.Del("Set-Cookie")
+
+	i := Interaction{
+		Request: r,
+		Response: Response{
+			StatusCode: res.StatusCode,
+			Status:     res.Status,
+			Header:     h,
+			Body:       pkg.MaskPersonnummer(string(b)),
+		},
+	}
+	if err := save(file, i); err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// next returns the file name for the request to the endpoint at urlPath, derived from the endpoint, the
+// sanitized body without ignoredFields and how many times the same request was made before, and that count
+func (c *Cassette) next(r Request, urlPath string) (string, int) {
+	h := sha256.Sum256([]byte(r.Method + " " + urlPath + "\n" + normalize(r.Body)))
+	key := fmt.Sprintf("%v-%v", strings.Trim(path.Base(urlPath), "/"), hex.EncodeToString(h[:6]))
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	n := c.seq[key]
+	c.seq[key]++
+	return fmt.Sprintf("%v-%d.json", key, n), n
+}
+
+// normalize removes ignoredFields from a JSON body at any depth. Other bodies are returned as is.
+func normalize(body string) string {
+	var v interface{}
+	if err := json.Unmarshal([]byte(body), &v); err != nil {
+		return body
+	}
+	b, err := json.Marshal(withoutIgnored(v))
+	if err != nil {
+		return body
+	}
+	return string(b)
+}
+
+func withoutIgnored(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, e := range v {
+			v[k] = withoutIgnored(e)
+			for _, f := range ignoredFields {
+				if strings.EqualFold(k, f) {
+					delete(v, k)
+				}
+			}
+		}
+	case []interface{}:
+		for i, e := range v {
+			v[i] = withoutIgnored(e)
+		}
+	}
+	return v
+}
+
+// response recreates the recorded response for req
+func (r Response) response(req *http.Request) *http.Response {
+	return &http.Response{
+		Status:        r.Status,
+		StatusCode:    r.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        r.Header,
+		Body:          io.NopCloser(strings.NewReader(r.Body)),
+		ContentLength: int64(len(r.Body)),
+		Request:       req,
+	}
+}
+
+func load(file string) (*Interaction, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var i Interaction
+	if err := json.NewDecoder(f).Decode(&i); err != nil {
+		return nil, fmt.Errorf("cassette: %v: %v", file, err)
+	}
+	return &i, nil
+}
+
+func save(file string, i Interaction) error {
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		return err
+	}
+
+	b, err := json.MarshalIndent(i, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(file, b, 0600)
+}
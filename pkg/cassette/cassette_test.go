@@ -0,0 +1,129 @@
+// Copyright © 2017 Sebastian Mandrean <sebastian.mandrean@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cassette_test
+
+import (
+	"context"
+	"github.com/mandrean/go-trafikverket/pkg"
+	"github.com/mandrean/go-trafikverket/pkg/cassette"
+	"github.com/mandrean/go-trafikverket/pkg/trafikverkettest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+const ssn = "199001010017"
+
+func request() *pkg.OccasionBundlesRequest {
+	return &pkg.OccasionBundlesRequest{
+		BookingSession:      pkg.BookingSession{SocialSecurityNumber: ssn, LicenceID: pkg.DefaultLicenceID},
+		OccasionBundleQuery: pkg.OccasionBundleQuery{LocationID: 1000140, ExaminationTypeID: trafikverkettest.DrivingExaminationTypeID},
+	}
+}
+
+func TestRecordReplay(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+	s := trafikverkettest.NewServer(trafikverkettest.DefaultFixtures(now))
+	defer s.Close()
+
+	// several pages of the same query, which only differ in their start dates
+	from, to := now, now.AddDate(0, 0, 35)
+	recorder := s.Client(cassette.New(dir, cassette.Record).ClientOption())
+	want, err := pkg.OccasionsBetween(context.Background(), recorder, request(), from, to)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pages := s.Requests("occasion-bundles")
+	if pages < 2 {
+		t.Fatalf("got %d pages, want a query spanning several", pages)
+	}
+
+	// replay later, with start dates derived from a later time
+	replayer := s.Client(cassette.New(dir, cassette.Replay).ClientOption())
+	got, err := pkg.OccasionsBetween(context.Background(), replayer, request(), from.Add(time.Minute), to)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %d replayed occasions, want the %d recorded", len(got), len(want))
+	}
+	if n := s.Requests("occasion-bundles"); n != pages {
+		t.Errorf("got %d requests to the server, want only the %d recorded", n, pages)
+	}
+
+	// personnummer are masked in the recordings
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != pages {
+		t.Errorf("got %d recordings, want %d", len(files), pages)
+	}
+	for _, f := range files {
+		b, err := os.ReadFile(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.Contains(string(b), ssn) {
+			t.Errorf("%v contains the social security number", filepath.Base(f))
+		}
+	}
+}
+
+func TestReplayMiss(t *testing.T) {
+	dir := t.TempDir()
+	s := trafikverkettest.NewServer(trafikverkettest.DefaultFixtures(time.Now()))
+	defer s.Close()
+
+	if _, _, err := s.Client(cassette.New(dir, cassette.Record).ClientOption()).Occasions(request()); err != nil {
+		t.Fatal(err)
+	}
+
+	other := request()
+	other.OccasionBundleQuery.LocationID = 1000071
+	tests := []struct {
+		name     string
+		requests []*pkg.OccasionBundlesRequest
+		want     string
+	}{
+		{"not recorded", []*pkg.OccasionBundlesRequest{other}, "cassette: no recorded interaction for POST /Boka/occasion-bundles #1"},
+		{"recorded fewer times", []*pkg.OccasionBundlesRequest{request(), request()}, "cassette: no recorded interaction for POST /Boka/occasion-bundles #2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := s.Client(cassette.New(dir, cassette.Replay).ClientOption())
+			var err error
+			for _, r := range tt.requests {
+				if _, _, err = c.Occasions(r); err != nil {
+					break
+				}
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("got error %v, want it to contain %q", err, tt.want)
+			}
+		})
+	}
+}
@@ -22,8 +22,14 @@ package pkg
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"github.com/mandrean/go-trafikverket/pkg/personnummer"
 	log "github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+	"io"
 	"net"
 	"net/http"
 	"net/url"
@@ -35,11 +41,40 @@ import (
 const (
 	TRAFIKVERKET_BASE_URL = "https://fp.trafikverket.se"
 	TRAFIKVERKET_BOKA_URL = TRAFIKVERKET_BASE_URL + "/Boka/"
+
+	// DefaultLicenceID is the licence used when none is specified and Trafikverket selects none (B, passenger car)
+	DefaultLicenceID = 5
 )
 
 type (
+	// Client is the Förarprov API, implemented by TrafikverketClient. Depend on it instead of
+	// TrafikverketClient to be able to swap in a mock or an in-memory implementation in tests.
+	Client interface {
+		LicenceInformation() (*LicenceInformationResponse, *http.Response, error)
+		LicenceInformationContext(ctx context.Context) (*LicenceInformationResponse, *http.Response, error)
+		LicenceCategories() (*[]LicenceCategory, *http.Response, error)
+		LicenceCategoriesContext(ctx context.Context) (*[]LicenceCategory, *http.Response, error)
+		SearchInformation(body *SearchInformationRequest) (*SearchInformationResponse, *http.Response, error)
+		SearchInformationContext(ctx context.Context, body *SearchInformationRequest) (*SearchInformationResponse, *http.Response, error)
+		Locations(body *SearchInformationRequest) (*[]Location, *http.Response, error)
+		LocationsContext(ctx context.Context, body *SearchInformationRequest) (*[]Location, *http.Response, error)
+		OccasionBundles(body *OccasionBundlesRequest) (*OccasionBundlesResponse, *http.Response, error)
+		OccasionBundlesContext(ctx context.Context, body *OccasionBundlesRequest) (*OccasionBundlesResponse, *http.Response, error)
+		Occasions(body *OccasionBundlesRequest) (*[]Occasion, *http.Response, error)
+		OccasionsContext(ctx context.Context, body *OccasionBundlesRequest) (*[]Occasion, *http.Response, error)
+		Bundles(body *OccasionBundlesRequest) (*[]Bundle, *http.Response, error)
+		BundlesContext(ctx context.Context, body *OccasionBundlesRequest) (*[]Bundle, *http.Response, error)
+	}
+
 	TrafikverketClient struct {
 		*http.Client
+
+		baseURL              string
+		tracer               trace.Tracer
+		validatePersonnummer bool
+		strict               bool
+		recorder             Recorder
+		decodeObserver       DecodeObserver
 	}
 
 	BookingSession struct {
@@ -49,10 +84,70 @@ type (
 		IgnoreDebt           bool   `yaml:"ignoreDebt"`
 		ExaminationTypeID    int    `yaml:"examinationTypeId"`
 	}
+
+	// ClientOption configures optional behaviour of a TrafikverketClient
+	ClientOption func(*TrafikverketClient)
+
+	// StatusError is returned for responses with a non-2xx status
+	StatusError struct {
+		StatusCode int
+		Status     string
+	}
+
+	// Recorder is called with every successful occasion bundles poll, e.g. for storing availability history
+	Recorder interface {
+		Record(t time.Time, body *OccasionBundlesRequest, resp *OccasionBundlesResponse) error
+	}
+
+	// DecodeObserver is called with every successful response that couldn't be decoded, e.g. for counting
+	// invalid JSON and schema drift
+	DecodeObserver interface {
+		ObserveDecodeError(endpoint string, err error)
+	}
 )
 
+var _ Client = (*TrafikverketClient)(nil)
+
+//go:generate mockgen -destination=mock/client.go -package=mock github.com/mandrean/go-trafikverket/pkg Client
+
+// WithRoundTripper wraps the client's transport, e.g. for instrumentation
+func WithRoundTripper(wrap func(next http.RoundTripper) http.RoundTripper) ClientOption {
+	return func(tc *TrafikverketClient) {
+		tc.Transport = wrap(tc.Transport)
+	}
+}
+
+// WithBaseURL sends requests to base instead of TRAFIKVERKET_BASE_URL, e.g. a fake server in tests
+func WithBaseURL(base string) ClientOption {
+	return func(tc *TrafikverketClient) {
+		tc.baseURL = strings.TrimSuffix(base, "/")
+	}
+}
+
+// WithPersonnummerValidation validates and normalises the social security number of every
+// BookingSession before sending it, failing early on malformed numbers
+func WithPersonnummerValidation() ClientOption {
+	return func(tc *TrafikverketClient) {
+		tc.validatePersonnummer = true
+	}
+}
+
+// WithRecorder records every successful occasion bundles poll with r
+func WithRecorder(r Recorder) ClientOption {
+	return func(tc *TrafikverketClient) {
+		tc.recorder = r
+	}
+}
+
+// WithDecodeObserver reports every response that couldn't be decoded to o
+func WithDecodeObserver(o DecodeObserver) ClientOption {
+	return func(tc *TrafikverketClient) {
+		tc.decodeObserver = o
+	}
+}
+
 // NewClient creates a new TrafikverketClient
-func NewClient() *TrafikverketClient {
+func NewClient(opts ...ClientOption) *TrafikverketClient {
 	t := &http.Transport{
 		Dial: (&net.Dialer{
 			Timeout: 5 * time.Second,
@@ -60,39 +155,147 @@ func NewClient() *TrafikverketClient {
 		TLSHandshakeTimeout: 5 * time.Second,
 	}
 
-	return &TrafikverketClient{
-		&http.Client{
+	tc := &TrafikverketClient{
+		Client: &http.Client{
 			Timeout:   time.Second * 10,
 			Transport: t,
 		},
+		baseURL: TRAFIKVERKET_BASE_URL,
+		tracer:  noop.NewTracerProvider().Tracer(tracerName),
+	}
+
+	for _, opt := range opts {
+		opt(tc)
 	}
+
+	return tc
 }
 
-// NewPostRequest creates a new *http.Request for the payload and sets the required headers
+// NewRequest creates a new *http.Request for the payload and sets the required headers
 func NewRequest(method string, resource string, payload interface{}) (*http.Request, error) {
+	return NewRequestWithContext(context.Background(), method, resource, payload)
+}
+
+// NewRequestWithContext is like NewRequest but with a context
+func NewRequestWithContext(ctx context.Context, method string, resource string, payload interface{}) (*http.Request, error) {
+	return newRequest(ctx, TRAFIKVERKET_BASE_URL, method, resource, payload)
+}
+
+// newRequest creates a new *http.Request for the payload against the Boka API at base
+func newRequest(ctx context.Context, base string, method string, resource string, payload interface{}) (*http.Request, error) {
 	// encode as json
 	b := new(bytes.Buffer)
 	json.NewEncoder(b).Encode(payload)
 
 	// join url
-	u, err := url.Parse(TRAFIKVERKET_BOKA_URL)
+	boka := base + "/Boka/"
+	u, err := url.Parse(boka)
+	if err != nil {
+		return nil, err
+	}
 	u.Path = path.Join(u.Path, resource)
 	s := u.String()
 
 	// create request
-	req, err := http.NewRequest(strings.ToUpper(method), s, b)
+	req, err := http.NewRequestWithContext(ctx, strings.ToUpper(method), s, b)
 	if err != nil {
 		return nil, err
 	}
 
 	// set headers
-	req.Header.Set("Origin", TRAFIKVERKET_BASE_URL)
-	req.Header.Set("Referer", TRAFIKVERKET_BOKA_URL)
+	req.Header.Set("Origin", base)
+	req.Header.Set("Referer", boka)
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("Accept", "application/json")
 
 	log.Debugf("%v %v", req.Method, req.URL)
-	log.Debugln(b.String())
+	log.Debugln(RedactString(b.String()))
 
 	return req, nil
 }
+
+// bookingSession validates and normalises bs if the client was configured to do so
+func (tc *TrafikverketClient) bookingSession(bs BookingSession) (BookingSession, error) {
+	if !tc.validatePersonnummer {
+		return bs, nil
+	}
+
+	ssn, err := personnummer.Normalise(bs.SocialSecurityNumber)
+	if err != nil {
+		return bs, err
+	}
+	bs.SocialSecurityNumber = ssn
+
+	return bs, nil
+}
+
+// do sends a request with the payload to resource and decodes the response into v
+func (tc *TrafikverketClient) do(ctx context.Context, resource string, payload interface{}, v interface{}) (*http.Response, error) {
+	endpoint := strings.TrimPrefix(resource, "/")
+	ctx, span := tc.tracer.Start(ctx, "POST "+resource,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("http.request.method", "POST"),
+			attribute.String("trafikverket.endpoint", endpoint),
+		),
+	)
+	defer span.End()
+
+	// create request
+	req, err := newRequest(ctx, tc.baseURL, "POST", resource, payload)
+	if err != nil {
+		return nil, endSpan(span, err)
+	}
+	span.SetAttributes(attribute.Int64("http.request.body.size", req.ContentLength))
+
+	// make request
+	res, err := tc.Do(req)
+	if err != nil {
+		return res, endSpan(span, err)
+	}
+	defer res.Body.Close()
+	span.SetAttributes(attribute.Int("http.response.status_code", res.StatusCode))
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return res, endSpan(span, &StatusError{StatusCode: res.StatusCode, Status: res.Status})
+	}
+
+	// decode response, comparing it with v first in strict mode
+	cr := &countingReader{r: res.Body}
+	if tc.strict {
+		var b []byte
+		if b, err = io.ReadAll(cr); err == nil {
+			err = decodeStrict(endpoint, b, v)
+		}
+	} else {
+		err = json.NewDecoder(cr).Decode(v)
+	}
+	span.SetAttributes(attribute.Int64("http.response.body.size", cr.n))
+	if err != nil {
+		if tc.decodeObserver != nil {
+			tc.decodeObserver.ObserveDecodeError(endpoint, err)
+		}
+		return res, endSpan(span, err)
+	}
+
+	b, _ := json.Marshal(v)
+	log.Debugln(RedactString(string(b)))
+
+	return res, nil
+}
+
+func (e *StatusError) Error() string {
+	return e.Status
+}
+
+// countingReader counts the bytes read through it
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,360 @@
+// Copyright © 2017 Sebastian Mandrean <sebastian.mandrean@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package pkg_test
+
+import (
+	"context"
+	"errors"
+	"github.com/mandrean/go-trafikverket/pkg"
+	"github.com/mandrean/go-trafikverket/pkg/trafikverkettest"
+	"net/http"
+	"sort"
+	"testing"
+	"time"
+)
+
+// stockholm is the location with English theory tests in DefaultFixtures
+const stockholm = 1000140
+
+// fakes returns the fake implementations of pkg.Client serving f, the fake server is closed when t is done
+func fakes(t *testing.T, f trafikverkettest.Fixtures) map[string]pkg.Client {
+	t.Helper()
+	s := trafikverkettest.NewServer(f)
+	t.Cleanup(s.Close)
+	return map[string]pkg.Client{
+		"server": s.Client(),
+		"static": trafikverkettest.NewStaticClient(f),
+	}
+}
+
+func TestClientEndpoints(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	f := trafikverkettest.DefaultFixtures(now)
+
+	// start the query on a fixed date, so the occasions returned don't depend on when the test runs
+	y, m, d := now.Date()
+	start := time.Date(y, m, d+1, 0, 0, 0, 0, time.Local)
+	si := &pkg.SearchInformationRequest{
+		BookingSession: pkg.BookingSession{SocialSecurityNumber: testSSN, LicenceID: pkg.DefaultLicenceID},
+	}
+	ob := &pkg.OccasionBundlesRequest{
+		BookingSession: si.BookingSession,
+		OccasionBundleQuery: pkg.OccasionBundleQuery{
+			StartDate:         start,
+			LocationID:        stockholm,
+			ExaminationTypeID: trafikverkettest.TheoryExaminationTypeID,
+		},
+	}
+
+	// the theory tests at the location within the fake's window, one bundle each
+	var theory []int
+	for _, o := range f.Occasions {
+		s := o.Duration.Start
+		if o.LocationID == stockholm && o.ExaminationTypeID == trafikverkettest.TheoryExaminationTypeID &&
+			!s.Before(start) && s.Before(start.Add(trafikverkettest.DefaultWindow)) {
+			theory = append(theory, int(s.Unix()))
+		}
+	}
+	sort.Ints(theory)
+	if len(theory) == 0 {
+		t.Fatal("the fixtures have no theory tests to expect")
+	}
+	locations := []int{1000140, 1000071, 1000072}
+
+	// each call returns the IDs of what it got
+	tests := []struct {
+		name string
+		call func(c pkg.Client) ([]int, error)
+		want []int
+	}{
+		{"LicenceInformation", func(c pkg.Client) ([]int, error) {
+			resp, _, err := c.LicenceInformation()
+			if err != nil {
+				return nil, err
+			}
+			return append([]int{resp.Data.LicenceID}, licenceIDs(&resp.Data.LicenceCategories)...), nil
+		}, []int{pkg.DefaultLicenceID, pkg.DefaultLicenceID}},
+		{"LicenceInformationContext", func(c pkg.Client) ([]int, error) {
+			resp, _, err := c.LicenceInformationContext(ctx)
+			if err != nil {
+				return nil, err
+			}
+			return append([]int{resp.Data.LicenceID}, licenceIDs(&resp.Data.LicenceCategories)...), nil
+		}, []int{pkg.DefaultLicenceID, pkg.DefaultLicenceID}},
+		{"LicenceCategories", func(c pkg.Client) ([]int, error) {
+			lcs, _, err := c.LicenceCategories()
+			return licenceIDs(lcs), err
+		}, []int{pkg.DefaultLicenceID}},
+		{"LicenceCategoriesContext", func(c pkg.Client) ([]int, error) {
+			lcs, _, err := c.LicenceCategoriesContext(ctx)
+			return licenceIDs(lcs), err
+		}, []int{pkg.DefaultLicenceID}},
+		{"SearchInformation", func(c pkg.Client) ([]int, error) {
+			resp, _, err := c.SearchInformation(si)
+			if err != nil {
+				return nil, err
+			}
+			return append([]int{int(resp.Data.LicenceID), int(resp.Data.LanguageID)}, locationIDs(&resp.Data.Locations)...), nil
+		}, append([]int{pkg.DefaultLicenceID, 13}, locations...)},
+		{"SearchInformationContext", func(c pkg.Client) ([]int, error) {
+			resp, _, err := c.SearchInformationContext(ctx, si)
+			if err != nil {
+				return nil, err
+			}
+			return append([]int{int(resp.Data.LicenceID), int(resp.Data.LanguageID)}, locationIDs(&resp.Data.Locations)...), nil
+		}, append([]int{pkg.DefaultLicenceID, 13}, locations...)},
+		{"Locations", func(c pkg.Client) ([]int, error) {
+			ls, _, err := c.Locations(si)
+			return locationIDs(ls), err
+		}, locations},
+		{"LocationsContext", func(c pkg.Client) ([]int, error) {
+			ls, _, err := c.LocationsContext(ctx, si)
+			return locationIDs(ls), err
+		}, locations},
+		{"OccasionBundles", func(c pkg.Client) ([]int, error) {
+			resp, _, err := c.OccasionBundles(ob)
+			if err != nil {
+				return nil, err
+			}
+			return bundleIDs(&resp.Data), nil
+		}, theory},
+		{"OccasionBundlesContext", func(c pkg.Client) ([]int, error) {
+			resp, _, err := c.OccasionBundlesContext(ctx, ob)
+			if err != nil {
+				return nil, err
+			}
+			return bundleIDs(&resp.Data), nil
+		}, theory},
+		{"Occasions", func(c pkg.Client) ([]int, error) {
+			os, _, err := c.Occasions(ob)
+			return occasionIDs(os), err
+		}, theory},
+		{"OccasionsContext", func(c pkg.Client) ([]int, error) {
+			os, _, err := c.OccasionsContext(ctx, ob)
+			return occasionIDs(os), err
+		}, theory},
+		{"Bundles", func(c pkg.Client) ([]int, error) {
+			bs, _, err := c.Bundles(ob)
+			return bundleIDs(bs), err
+		}, theory},
+		{"BundlesContext", func(c pkg.Client) ([]int, error) {
+			bs, _, err := c.BundlesContext(ctx, ob)
+			return bundleIDs(bs), err
+		}, theory},
+	}
+
+	cs := fakes(t, f)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for name, c := range cs {
+				got, err := tt.call(c)
+				if err != nil {
+					t.Fatalf("%v: %v", name, err)
+				}
+				if !equalInts(got, tt.want) {
+					t.Errorf("%v: got %v, want %v", name, got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestClientOccasionsMatchQuery(t *testing.T) {
+	body := &pkg.OccasionBundlesRequest{
+		BookingSession:      pkg.BookingSession{SocialSecurityNumber: testSSN, LicenceID: pkg.DefaultLicenceID},
+		OccasionBundleQuery: pkg.OccasionBundleQuery{LocationID: stockholm, ExaminationTypeID: trafikverkettest.TheoryExaminationTypeID},
+	}
+
+	for name, c := range fakes(t, trafikverkettest.DefaultFixtures(time.Now())) {
+		t.Run(name, func(t *testing.T) {
+			os, res, err := c.Occasions(body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if res.StatusCode != http.StatusOK {
+				t.Errorf("got status %d, want 200", res.StatusCode)
+			}
+			if len(*os) == 0 {
+				t.Fatal("got no occasions")
+			}
+			for i, o := range *os {
+				if o.LocationID != stockholm || o.ExaminationTypeID != trafikverkettest.TheoryExaminationTypeID {
+					t.Errorf("got occasion at %d of type %d, want %d of type %d", o.LocationID, o.ExaminationTypeID, stockholm, trafikverkettest.TheoryExaminationTypeID)
+				}
+				if i > 0 && o.Duration.Start.Before((*os)[i-1].Duration.Start) {
+					t.Errorf("occasion %d starts before the previous one", i)
+				}
+			}
+		})
+	}
+}
+
+func TestClientInvalidPersonnummer(t *testing.T) {
+	body := &pkg.SearchInformationRequest{
+		BookingSession: pkg.BookingSession{SocialSecurityNumber: "199001010018", LicenceID: pkg.DefaultLicenceID},
+	}
+
+	for name, c := range fakes(t, trafikverkettest.DefaultFixtures(time.Now())) {
+		t.Run(name, func(t *testing.T) {
+			if _, _, err := c.Locations(body); err == nil {
+				t.Error("got no error for an invalid checksum")
+			}
+		})
+	}
+}
+
+func TestRaw(t *testing.T) {
+	s := trafikverkettest.NewServer(trafikverkettest.DefaultFixtures(time.Now()))
+	defer s.Close()
+	c := s.Client()
+
+	tests := []struct {
+		name   string
+		body   string
+		err    error
+		status int
+	}{
+		{"valid", `{"bookingSession": {"socialSecurityNumber": "` + testSSN + `", "licenceId": 5}}`, nil, http.StatusOK},
+		{"empty", "", nil, http.StatusBadRequest},
+		{"invalid", `{"bookingSession": `, pkg.ErrInvalidJSON, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := s.Requests("search-information")
+			res, err := c.Raw(context.Background(), "post", "/Boka/search-information", []byte(tt.body))
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("got error %v, want %v", err, tt.err)
+			}
+			if err != nil {
+				if n := s.Requests("search-information") - before; n != 0 {
+					t.Errorf("got %d requests for an invalid body, want none", n)
+				}
+				return
+			}
+			defer res.Body.Close()
+			if res.StatusCode != tt.status {
+				t.Errorf("got status %d, want %d", res.StatusCode, tt.status)
+			}
+		})
+	}
+}
+
+func TestServerFailNext(t *testing.T) {
+	s := trafikverkettest.NewServer(trafikverkettest.DefaultFixtures(time.Now()))
+	defer s.Close()
+	var c pkg.Client = s.Client()
+	body := &pkg.OccasionBundlesRequest{
+		BookingSession:      pkg.BookingSession{SocialSecurityNumber: testSSN, LicenceID: pkg.DefaultLicenceID},
+		OccasionBundleQuery: pkg.OccasionBundleQuery{LocationID: stockholm},
+	}
+
+	s.FailNext("occasion-bundles", http.StatusServiceUnavailable)
+	_, _, err := c.Occasions(body)
+	var se *pkg.StatusError
+	if !errors.As(err, &se) || se.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("got error %v, want a 503 *pkg.StatusError", err)
+	}
+
+	// the failure is consumed
+	if _, _, err := c.Occasions(body); err != nil {
+		t.Errorf("got error %v after the failure", err)
+	}
+	if n := s.Requests("occasion-bundles"); n != 2 {
+		t.Errorf("got %d requests, want 2", n)
+	}
+}
+
+func TestServerRateLimit(t *testing.T) {
+	s := trafikverkettest.NewServer(trafikverkettest.DefaultFixtures(time.Now()))
+	defer s.Close()
+	var c pkg.Client = s.Client()
+	s.SetRateLimit(1, time.Hour)
+
+	if _, _, err := c.LicenceInformation(); err != nil {
+		t.Fatal(err)
+	}
+	_, _, err := c.LicenceInformation()
+	var se *pkg.StatusError
+	if !errors.As(err, &se) || se.StatusCode != http.StatusTooManyRequests {
+		t.Errorf("got error %v, want a 429 *pkg.StatusError", err)
+	}
+}
+
+func licenceIDs(lcs *[]pkg.LicenceCategory) []int {
+	if lcs == nil {
+		return nil
+	}
+	var ids []int
+	for _, lc := range *lcs {
+		for _, l := range lc.Licences {
+			ids = append(ids, l.ID)
+		}
+	}
+	return ids
+}
+
+func locationIDs(ls *[]pkg.Location) []int {
+	if ls == nil {
+		return nil
+	}
+	var ids []int
+	for _, l := range *ls {
+		ids = append(ids, int(l.ID))
+	}
+	return ids
+}
+
+// occasionIDs returns the start of each occasion, as the fixtures have no examination IDs
+func occasionIDs(os *[]pkg.Occasion) []int {
+	if os == nil {
+		return nil
+	}
+	var ids []int
+	for _, o := range *os {
+		ids = append(ids, int(o.Duration.Start.Unix()))
+	}
+	return ids
+}
+
+func bundleIDs(bs *[]pkg.Bundle) []int {
+	if bs == nil {
+		return nil
+	}
+	var ids []int
+	for _, b := range *bs {
+		ids = append(ids, occasionIDs(&b.Occasions)...)
+	}
+	return ids
+}
+
+func equalInts(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
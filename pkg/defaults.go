@@ -0,0 +1,119 @@
+// Copyright © 2017 Sebastian Mandrean <sebastian.mandrean@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package pkg
+
+import (
+	"context"
+	log "github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// DefaultLicence returns the licence Trafikverket selects by default, or DefaultLicenceID if it selects none
+func DefaultLicence(ctx context.Context, client Client) (int, error) {
+	resp, _, err := client.LicenceInformationContext(ctx)
+	if err != nil {
+		return 0, err
+	}
+	if resp.Data.LicenceID == 0 {
+		return DefaultLicenceID, nil
+	}
+	return resp.Data.LicenceID, nil
+}
+
+// ApplyDefaults fills in the IDs of q left as 0 with the defaults in info, the search information for the
+// booking session. Fields info says are not applicable to the licence, like the tachograph type of a passenger
+// car, are always set to the default, and the yaml names of those q had another value for are returned as ignored.
+// An ExaminationTypeID of 0 is kept, as it asks Trafikverket for bundles of all the examinations still to take.
+func ApplyDefaults(info *SearchInformationResponse, q OccasionBundleQuery) (OccasionBundleQuery, []string) {
+	d := info.Data
+	var ignored []string
+	for _, f := range []struct {
+		name string
+		v    *int
+		def  uint64
+		show bool
+		fill bool
+	}{
+		{"languageId", &q.LanguageID, d.LanguageID, d.ShowLanguage, true},
+		{"vehicleTypeId", &q.VehicleTypeID, d.VehicleTypeID, d.ShowVehicleType, true},
+		{"tachographTypeId", &q.TachographTypeID, d.TachographTypeID, d.ShowTachographType, true},
+		{"occasionChoiceId", &q.OccasionChoiceID, d.OccasionChoiceID, d.ShowOccasionChoices, true},
+		{"examinationTypeId", &q.ExaminationTypeID, d.ExaminationTypeID, d.ShowExaminationType, false},
+	} {
+		switch {
+		case *f.v == 0:
+			if f.fill {
+				*f.v = int(f.def)
+			}
+		case !f.show && *f.v != int(f.def):
+			ignored = append(ignored, f.name)
+			*f.v = int(f.def)
+		}
+	}
+
+	return q, ignored
+}
+
+// ResolveQuery returns a copy of body with its licence and query IDs left as 0 filled in with Trafikverket's
+// defaults, see ApplyDefaults, and validates the result with CheckQuery against the same search information. Ignored fields are logged as warnings.
+func ResolveQuery(ctx context.Context, client Client, body *OccasionBundlesRequest) (*OccasionBundlesRequest, error) {
+	ctx, span := tracerFor(client).Start(ctx, "ResolveQuery", trace.WithAttributes(
+		attribute.Int("trafikverket.licence_id", body.BookingSession.LicenceID),
+		attribute.Int("trafikverket.location_id", body.OccasionBundleQuery.LocationID),
+	))
+	defer span.End()
+
+	b, info, err := resolveQuery(ctx, client, body)
+	if err != nil {
+		return nil, endSpan(span, err)
+	}
+	if err := CheckQuery(info, b.OccasionBundleQuery); err != nil {
+		return nil, endSpan(span, err)
+	}
+
+	return b, nil
+}
+
+// resolveQuery is ResolveQuery without the validation, returning the search information to validate against
+func resolveQuery(ctx context.Context, client Client, body *OccasionBundlesRequest) (*OccasionBundlesRequest, *SearchInformationResponse, error) {
+	b := *body
+	if b.BookingSession.LicenceID == 0 {
+		id, err := DefaultLicence(ctx, client)
+		if err != nil {
+			return nil, nil, err
+		}
+		b.BookingSession.LicenceID = id
+	}
+
+	info, err := searchInformationFor(ctx, client, &b)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	q, ignored := ApplyDefaults(info, b.OccasionBundleQuery)
+	for _, f := range ignored {
+		log.Warnf("%v is not applicable to licence %d and was ignored", f, b.BookingSession.LicenceID)
+	}
+	b.OccasionBundleQuery = q
+
+	return &b, info, nil
+}
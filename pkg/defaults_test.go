@@ -0,0 +1,158 @@
+// Copyright © 2017 Sebastian Mandrean <sebastian.mandrean@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package pkg_test
+
+import (
+	"context"
+	"errors"
+	"github.com/mandrean/go-trafikverket/pkg"
+	"github.com/mandrean/go-trafikverket/pkg/mock"
+	"github.com/mandrean/go-trafikverket/pkg/trafikverkettest"
+	"go.uber.org/mock/gomock"
+	"testing"
+	"time"
+)
+
+func TestDefaultLicence(t *testing.T) {
+	unavailable := &pkg.StatusError{StatusCode: 503, Status: "503 Service Unavailable"}
+
+	tests := []struct {
+		name      string
+		licenceID int
+		err       error
+		want      int
+	}{
+		{"selected by Trafikverket", 17, nil, 17},
+		{"none selected", 0, nil, pkg.DefaultLicenceID},
+		{"error", 0, unavailable, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := mock.NewMockClient(gomock.NewController(t))
+			resp := &pkg.LicenceInformationResponse{}
+			resp.Data.LicenceID = tt.licenceID
+			m.EXPECT().LicenceInformationContext(gomock.Any()).Return(resp, nil, tt.err)
+
+			got, err := pkg.DefaultLicence(context.Background(), m)
+			if !errors.Is(err, tt.err) {
+				t.Errorf("got error %v, want %v", err, tt.err)
+			}
+			if got != tt.want {
+				t.Errorf("got licence %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveQuery(t *testing.T) {
+	m := mock.NewMockClient(gomock.NewController(t))
+	licence := &pkg.LicenceInformationResponse{}
+	licence.Data.LicenceID = pkg.DefaultLicenceID
+	info := &pkg.SearchInformationResponse{}
+	info.Data.LicenceID = pkg.DefaultLicenceID
+	info.Data.Locations = []pkg.Location{{ID: stockholm}}
+	info.Data.LanguageID = 13
+	info.Data.VehicleTypeID = 1
+	info.Data.TachographTypeID = 1
+	info.Data.ShowLanguage = true
+	info.Data.ShowVehicleType = true
+	info.Data.ShowExaminationType = true
+
+	// the examination type of the query is searched for, as it decides the locations and languages
+	m.EXPECT().LicenceInformationContext(gomock.Any()).Return(licence, nil, nil)
+	m.EXPECT().SearchInformationContext(gomock.Any(), &pkg.SearchInformationRequest{
+		BookingSession: pkg.BookingSession{SocialSecurityNumber: testSSN, LicenceID: pkg.DefaultLicenceID, ExaminationTypeID: 3},
+	}).Return(info, nil, nil)
+
+	body, err := pkg.ResolveQuery(context.Background(), m, &pkg.OccasionBundlesRequest{
+		BookingSession:      pkg.BookingSession{SocialSecurityNumber: testSSN},
+		OccasionBundleQuery: pkg.OccasionBundleQuery{LocationID: stockholm, VehicleTypeID: 4, TachographTypeID: 2, ExaminationTypeID: 3},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := pkg.OccasionBundleQuery{LocationID: stockholm, LanguageID: 13, VehicleTypeID: 4, TachographTypeID: 1, ExaminationTypeID: 3}
+	if body.BookingSession.LicenceID != pkg.DefaultLicenceID || body.OccasionBundleQuery != want {
+		t.Errorf("got licence %d and query %+v, want %d and %+v", body.BookingSession.LicenceID, body.OccasionBundleQuery, pkg.DefaultLicenceID, want)
+	}
+}
+
+func TestResolveQueryKeepsBundlesCombined(t *testing.T) {
+	f := trafikverkettest.DefaultFixtures(time.Now())
+	f.Combined = true
+
+	for name, c := range fakes(t, f) {
+		t.Run(name, func(t *testing.T) {
+			// resolve and fetch like list bundles does without --examination-type-id
+			body, err := pkg.ResolveQuery(context.Background(), c, &pkg.OccasionBundlesRequest{
+				BookingSession:      pkg.BookingSession{SocialSecurityNumber: testSSN},
+				OccasionBundleQuery: pkg.OccasionBundleQuery{LocationID: stockholm},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if body.OccasionBundleQuery.ExaminationTypeID != 0 {
+				t.Errorf("got examination type %d, want 0 for bundles of all examinations", body.OccasionBundleQuery.ExaminationTypeID)
+			}
+
+			bs, _, err := c.Bundles(body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(*bs) == 0 {
+				t.Fatal("got no bundles")
+			}
+			for i, b := range *bs {
+				if !b.Combined() || !b.Ordered() {
+					t.Fatalf("bundle %d isn't a theory test followed by a driving test: %+v", i, b.Occasions)
+				}
+				if b.Occasions[0].ExaminationTypeID != trafikverkettest.TheoryExaminationTypeID || b.Occasions[1].ExaminationTypeID != trafikverkettest.DrivingExaminationTypeID {
+					t.Errorf("bundle %d has examination types %d and %d", i, b.Occasions[0].ExaminationTypeID, b.Occasions[1].ExaminationTypeID)
+				}
+			}
+		})
+	}
+}
+
+func TestResolveQueryValidates(t *testing.T) {
+	for name, c := range fakes(t, trafikverkettest.DefaultFixtures(time.Now())) {
+		t.Run(name, func(t *testing.T) {
+			// English is only offered in Stockholm
+			body := &pkg.OccasionBundlesRequest{
+				BookingSession:      pkg.BookingSession{SocialSecurityNumber: testSSN, LicenceID: pkg.DefaultLicenceID},
+				OccasionBundleQuery: pkg.OccasionBundleQuery{LocationID: 1000071, LanguageID: 4},
+			}
+
+			var resolveErr, validateErr *pkg.ValidationError
+			if _, err := pkg.ResolveQuery(context.Background(), c, body); !errors.As(err, &resolveErr) {
+				t.Fatalf("ResolveQuery: got error %v, want a *ValidationError", err)
+			}
+			if err := pkg.ValidateQuery(context.Background(), c, body); !errors.As(err, &validateErr) {
+				t.Fatalf("ValidateQuery: got error %v, want a *ValidationError", err)
+			}
+			if resolveErr.Error() != validateErr.Error() {
+				t.Errorf("ResolveQuery: got %v, want the same as ValidateQuery: %v", resolveErr, validateErr)
+			}
+		})
+	}
+}
@@ -21,9 +21,8 @@
 package pkg
 
 import (
-	"encoding/json"
-	"errors"
-	log "github.com/sirupsen/logrus"
+	"context"
+	"go.opentelemetry.io/otel/attribute"
 	"net/http"
 )
 
@@ -40,55 +39,50 @@ type (
 	}
 
 	LicenceCategory struct {
-		Name     string `yaml:"name"`
-		Licences []struct {
-			ID          int    `yaml:"id"`
-			Name        string `yaml:"name"`
-			Description string `yaml:"description"`
-			Category    string `yaml:"category"`
-			Icon        string `yaml:"icon"`
-		} `yaml:"licences"`
+		Name     string    `yaml:"name"`
+		Licences []Licence `yaml:"licences"`
+	}
+
+	Licence struct {
+		ID          int    `yaml:"id"`
+		Name        string `yaml:"name"`
+		Description string `yaml:"description"`
+		Category    string `yaml:"category"`
+		Icon        string `yaml:"icon"`
 	}
 )
 
 // LicenceInformation returns information about different driver's licence types available for booking exams for
 func (tc *TrafikverketClient) LicenceInformation() (*LicenceInformationResponse, *http.Response, error) {
-	// create request
-	req, err := NewRequest("POST", "/licence-information", "{}")
-	if err != nil {
-		return nil, nil, err
-	}
-
-	// make request
-	res, err := tc.Do(req)
-	if err != nil {
-		return nil, res, err
-	}
-	defer res.Body.Close()
-
-	if res.StatusCode < 200 || res.StatusCode > 299 {
-		return nil, res, errors.New(res.Status)
-	}
+	return tc.LicenceInformationContext(context.Background())
+}
 
-	// decode response
+// LicenceInformationContext is like LicenceInformation but with a context
+func (tc *TrafikverketClient) LicenceInformationContext(ctx context.Context) (*LicenceInformationResponse, *http.Response, error) {
 	var resp LicenceInformationResponse
-	err = json.NewDecoder(res.Body).Decode(&resp)
+	res, err := tc.do(ctx, "/licence-information", "{}", &resp)
 	if err != nil {
 		return nil, res, err
 	}
 
-	b, _ := json.Marshal(resp)
-	log.Debugln(string(b))
-
 	return &resp, res, nil
 }
 
 // LicenceCategories returns the different driver's licence categories available for booking exams for
 func (tc *TrafikverketClient) LicenceCategories() (*[]LicenceCategory, *http.Response, error) {
-	resp, res, err := tc.LicenceInformation()
+	return tc.LicenceCategoriesContext(context.Background())
+}
+
+// LicenceCategoriesContext is like LicenceCategories but with a context
+func (tc *TrafikverketClient) LicenceCategoriesContext(ctx context.Context) (*[]LicenceCategory, *http.Response, error) {
+	ctx, span := tc.tracer.Start(ctx, "LicenceCategories")
+	defer span.End()
+
+	resp, res, err := tc.LicenceInformationContext(ctx)
 	if err != nil {
-		return nil, res, err
+		return nil, res, endSpan(span, err)
 	}
+	span.SetAttributes(attribute.Int("trafikverket.results", len(resp.Data.LicenceCategories)))
 
 	return &resp.Data.LicenceCategories, res, nil
 }
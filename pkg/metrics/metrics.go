@@ -0,0 +1,176 @@
+// Copyright © 2017 Sebastian Mandrean <sebastian.mandrean@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package metrics
+
+import (
+	"errors"
+	"fmt"
+	"github.com/mandrean/go-trafikverket/pkg"
+	"github.com/prometheus/client_golang/prometheus"
+	"net"
+	"net/http"
+	"path"
+	"strconv"
+	"time"
+)
+
+const namespace = "trafikverket"
+
+// endpoints are the endpoint label values, any other path is labelled "other" to bound the cardinality
+var endpoints = map[string]bool{
+	"licence-information": true,
+	"search-information":  true,
+	"occasion-bundles":    true,
+}
+
+type (
+	// Metrics collects Prometheus metrics for a TrafikverketClient
+	Metrics struct {
+		requests *prometheus.CounterVec
+		errors   *prometheus.CounterVec
+		latency  *prometheus.HistogramVec
+		earliest *prometheus.GaugeVec
+	}
+
+	roundTripper struct {
+		m    *Metrics
+		next http.RoundTripper
+	}
+)
+
+// New creates a new Metrics and registers its collectors with reg
+func New(reg prometheus.Registerer) *Metrics {
+	m := &Metrics{
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "requests_total",
+			Help:      "Number of requests to the Förarprov API by endpoint and HTTP status code.",
+		}, []string{"endpoint", "code"}),
+		errors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "request_errors_total",
+			Help:      "Number of failed requests to the Förarprov API by endpoint and error class.",
+		}, []string{"endpoint", "class"}),
+		latency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "request_duration_seconds",
+			Help:      "Latency of requests to the Förarprov API by endpoint.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"endpoint"}),
+		earliest: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "earliest_occasion_timestamp_seconds",
+			Help:      "Start time of the earliest available exam occasion by location and examination type.",
+		}, []string{"location_id", "examination_type_id"}),
+	}
+
+	reg.MustRegister(m.requests, m.errors, m.latency, m.earliest)
+
+	return m
+}
+
+// ClientOption instruments a TrafikverketClient with m, for use with pkg.NewClient
+func (m *Metrics) ClientOption() pkg.ClientOption {
+	return func(tc *pkg.TrafikverketClient) {
+		pkg.WithRoundTripper(m.RoundTripper)(tc)
+		pkg.WithDecodeObserver(m)(tc)
+	}
+}
+
+// RoundTripper wraps next, recording request counts, latencies and errors per endpoint
+func (m *Metrics) RoundTripper(next http.RoundTripper) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &roundTripper{m: m, next: next}
+}
+
+// ObserveDecodeError counts a response that couldn't be decoded as a schema or decode error,
+// implementing pkg.DecodeObserver
+func (m *Metrics) ObserveDecodeError(endpoint string, err error) {
+	class := "decode"
+	var se *pkg.SchemaError
+	if errors.As(err, &se) {
+		class = "schema"
+	}
+	m.errors.WithLabelValues(endpointLabel(endpoint), class).Inc()
+}
+
+// ObserveOccasions records the earliest of the occasions returned for query
+func (m *Metrics) ObserveOccasions(query pkg.OccasionBundleQuery, os []pkg.Occasion) {
+	l := []string{strconv.Itoa(query.LocationID), strconv.Itoa(query.ExaminationTypeID)}
+
+	var earliest time.Time
+	for _, o := range os {
+		if earliest.IsZero() || o.Duration.Start.Before(earliest) {
+			earliest = o.Duration.Start
+		}
+	}
+
+	// no slots available: remove the series instead of reporting a stale value
+	if earliest.IsZero() {
+		m.earliest.DeleteLabelValues(l...)
+		return
+	}
+	m.earliest.WithLabelValues(l...).Set(float64(earliest.Unix()))
+}
+
+func (rt *roundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	endpoint := endpointLabel(path.Base(req.URL.Path))
+
+	start := time.Now()
+	res, err := rt.next.RoundTrip(req)
+	rt.m.latency.WithLabelValues(endpoint).Observe(time.Since(start).Seconds())
+
+	if err != nil {
+		rt.m.requests.WithLabelValues(endpoint, "").Inc()
+		rt.m.errors.WithLabelValues(endpoint, errorClass(err)).Inc()
+		return res, err
+	}
+
+	rt.m.requests.WithLabelValues(endpoint, strconv.Itoa(res.StatusCode)).Inc()
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		rt.m.errors.WithLabelValues(endpoint, fmt.Sprintf("http_%dxx", res.StatusCode/100)).Inc()
+	}
+
+	return res, nil
+}
+
+// endpointLabel returns the endpoint label value for endpoint
+func endpointLabel(endpoint string) string {
+	if !endpoints[endpoint] {
+		return "other"
+	}
+	return endpoint
+}
+
+// errorClass classifies transport errors into a small set of label values
+func errorClass(err error) string {
+	var ne net.Error
+	if errors.As(err, &ne) && ne.Timeout() {
+		return "timeout"
+	}
+	var oe *net.OpError
+	if errors.As(err, &oe) {
+		return "network"
+	}
+	return "other"
+}
@@ -0,0 +1,141 @@
+// Copyright © 2017 Sebastian Mandrean <sebastian.mandrean@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package metrics_test
+
+import (
+	"context"
+	"github.com/mandrean/go-trafikverket/pkg"
+	"github.com/mandrean/go-trafikverket/pkg/metrics"
+	"github.com/mandrean/go-trafikverket/pkg/trafikverkettest"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testSSN = "199001010017"
+
+func TestRequests(t *testing.T) {
+	s := trafikverkettest.NewServer(trafikverkettest.DefaultFixtures(time.Now()))
+	defer s.Close()
+	reg := prometheus.NewRegistry()
+	c := s.Client(metrics.New(reg).ClientOption())
+	body := &pkg.OccasionBundlesRequest{
+		BookingSession:      pkg.BookingSession{SocialSecurityNumber: testSSN, LicenceID: pkg.DefaultLicenceID},
+		OccasionBundleQuery: pkg.OccasionBundleQuery{LocationID: 1000140},
+	}
+
+	if _, _, err := c.LicenceInformation(); err != nil {
+		t.Fatal(err)
+	}
+	s.FailNext("occasion-bundles", http.StatusServiceUnavailable)
+	if _, _, err := c.Occasions(body); err == nil {
+		t.Fatal("got no error for a 503")
+	}
+	if _, _, err := c.Occasions(body); err != nil {
+		t.Fatal(err)
+	}
+	res, err := c.Raw(context.Background(), "POST", "unknown", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+
+	want := `
+# HELP trafikverket_requests_total Number of requests to the Förarprov API by endpoint and HTTP status code.
+# TYPE trafikverket_requests_total counter
+trafikverket_requests_total{code="200",endpoint="licence-information"} 1
+trafikverket_requests_total{code="200",endpoint="occasion-bundles"} 1
+trafikverket_requests_total{code="503",endpoint="occasion-bundles"} 1
+trafikverket_requests_total{code="404",endpoint="other"} 1
+# HELP trafikverket_request_errors_total Number of failed requests to the Förarprov API by endpoint and error class.
+# TYPE trafikverket_request_errors_total counter
+trafikverket_request_errors_total{class="http_5xx",endpoint="occasion-bundles"} 1
+trafikverket_request_errors_total{class="http_4xx",endpoint="other"} 1
+`
+	if err := testutil.GatherAndCompare(reg, strings.NewReader(want), "trafikverket_requests_total", "trafikverket_request_errors_total"); err != nil {
+		t.Error(err)
+	}
+	if n := testutil.CollectAndCount(reg, "trafikverket_request_duration_seconds"); n != 3 {
+		t.Errorf("got latencies for %d endpoints, want 3", n)
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	bodies := map[string]string{
+		"search-information": `{"data":`,
+		"occasion-bundles":   `{"data":[{"occasions":[{"locationId":"1000140"}]}]}`,
+	}
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(bodies[path.Base(r.URL.Path)]))
+	}))
+	defer s.Close()
+	reg := prometheus.NewRegistry()
+	c := pkg.NewClient(pkg.WithBaseURL(s.URL), pkg.WithStrictDecoding(), metrics.New(reg).ClientOption())
+
+	if _, _, err := c.SearchInformation(&pkg.SearchInformationRequest{}); err == nil {
+		t.Fatal("got no error for invalid JSON")
+	}
+	if _, _, err := c.OccasionBundles(&pkg.OccasionBundlesRequest{}); err == nil {
+		t.Fatal("got no error for a changed type")
+	}
+
+	want := `
+# HELP trafikverket_request_errors_total Number of failed requests to the Förarprov API by endpoint and error class.
+# TYPE trafikverket_request_errors_total counter
+trafikverket_request_errors_total{class="decode",endpoint="search-information"} 1
+trafikverket_request_errors_total{class="schema",endpoint="occasion-bundles"} 1
+`
+	if err := testutil.GatherAndCompare(reg, strings.NewReader(want), "trafikverket_request_errors_total"); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestObserveOccasions(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	m := metrics.New(reg)
+	query := pkg.OccasionBundleQuery{LocationID: 1000140, ExaminationTypeID: 12}
+	earliest := time.Date(2030, 6, 3, 8, 0, 0, 0, time.UTC)
+	os := make([]pkg.Occasion, 2)
+	os[0].Duration.Start = earliest.Add(5 * time.Hour)
+	os[1].Duration.Start = earliest
+
+	m.ObserveOccasions(query, os)
+	want := `
+# HELP trafikverket_earliest_occasion_timestamp_seconds Start time of the earliest available exam occasion by location and examination type.
+# TYPE trafikverket_earliest_occasion_timestamp_seconds gauge
+trafikverket_earliest_occasion_timestamp_seconds{examination_type_id="12",location_id="1000140"} 1.906704e+09
+`
+	if err := testutil.GatherAndCompare(reg, strings.NewReader(want), "trafikverket_earliest_occasion_timestamp_seconds"); err != nil {
+		t.Error(err)
+	}
+
+	// no occasions left removes the series
+	m.ObserveOccasions(query, nil)
+	if n := testutil.CollectAndCount(reg, "trafikverket_earliest_occasion_timestamp_seconds"); n != 0 {
+		t.Errorf("got %d series, want none", n)
+	}
+}
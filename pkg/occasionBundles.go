@@ -21,11 +21,14 @@
 package pkg
 
 import (
-	"encoding/json"
-	"errors"
+	"context"
+	"fmt"
 	log "github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"net/http"
 	"time"
+	"unicode"
 )
 
 type (
@@ -45,12 +48,16 @@ type (
 	}
 
 	OccasionBundlesResponse struct {
-		Data []struct {
-			Occasions []Occasion `yaml:"occasions"`
-			Cost      string     `yaml:"cost"`
-		} `yaml:"data"`
-		Status int    `yaml:"status"`
-		URL    string `yaml:"url"`
+		Data   []Bundle `yaml:"data"`
+		Status int      `yaml:"status"`
+		URL    string   `yaml:"url"`
+	}
+
+	// Bundle is a set of occasions booked together, e.g. a theory test and a driving test, in the order they
+	// are taken. Cost is the total cost of the bundle.
+	Bundle struct {
+		Occasions []Occasion `yaml:"occasions"`
+		Cost      string     `yaml:"cost"`
 	}
 
 	Occasion struct {
@@ -80,41 +87,119 @@ type (
 
 // OccasionBundles returns the occasion bundles for the specified parameters
 func (tc *TrafikverketClient) OccasionBundles(body *OccasionBundlesRequest) (*OccasionBundlesResponse, *http.Response, error) {
-	// create request
-	req, err := NewRequest("POST", "/occasion-bundles", &body)
-	if err != nil {
-		return nil, nil, err
-	}
+	return tc.OccasionBundlesContext(context.Background(), body)
+}
 
-	// make request
-	res, err := tc.Do(req)
+// OccasionBundlesContext is like OccasionBundles but with a context
+func (tc *TrafikverketClient) OccasionBundlesContext(ctx context.Context, body *OccasionBundlesRequest) (*OccasionBundlesResponse, *http.Response, error) {
+	bs, err := tc.bookingSession(body.BookingSession)
 	if err != nil {
 		return nil, nil, err
 	}
-	defer res.Body.Close()
+	b := *body
+	b.BookingSession = bs
 
-	if res.StatusCode < 200 || res.StatusCode > 299 {
-		return nil, res, errors.New(res.Status)
-	}
-
-	// decode response
 	var resp OccasionBundlesResponse
-	err = json.NewDecoder(res.Body).Decode(&resp)
+	res, err := tc.do(ctx, "/occasion-bundles", &b, &resp)
 	if err != nil {
 		return nil, res, err
 	}
 
-	b, _ := json.Marshal(resp)
-	log.Debugln(string(b))
+	// recording is best effort and never fails the request
+	if tc.recorder != nil {
+		if err := tc.recorder.Record(time.Now(), &b, &resp); err != nil {
+			log.Warnln(RedactError(err))
+		}
+	}
 
 	return &resp, res, nil
 }
 
+// Start returns the start of the first occasion of the bundle
+func (b Bundle) Start() time.Time {
+	if len(b.Occasions) == 0 {
+		return time.Time{}
+	}
+	return b.Occasions[0].Duration.Start
+}
+
+// End returns the end of the last occasion of the bundle
+func (b Bundle) End() time.Time {
+	if len(b.Occasions) == 0 {
+		return time.Time{}
+	}
+	return b.Occasions[len(b.Occasions)-1].Duration.End
+}
+
+// Combined reports whether the bundle consists of more than one occasion
+func (b Bundle) Combined() bool {
+	return len(b.Occasions) > 1
+}
+
+// Ordered reports whether every occasion of the bundle ends before the next one starts
+func (b Bundle) Ordered() bool {
+	for i := 1; i < len(b.Occasions); i++ {
+		if b.Occasions[i].Duration.Start.Before(b.Occasions[i-1].Duration.End) {
+			return false
+		}
+	}
+	return true
+}
+
+// TotalCost returns the cost of the bundle in kronor, summing the cost of its occasions if the bundle has none
+func (b Bundle) TotalCost() (int, error) {
+	if b.Cost != "" {
+		return ParseCost(b.Cost)
+	}
+
+	total := 0
+	for _, o := range b.Occasions {
+		c, err := ParseCost(o.Cost)
+		if err != nil {
+			return 0, err
+		}
+		total += c
+	}
+	return total, nil
+}
+
+// ParseCost parses a cost like "1 125 kr" into kronor
+func ParseCost(s string) (int, error) {
+	n, digits := 0, false
+	for _, r := range s {
+		if unicode.IsDigit(r) {
+			n = n*10 + int(r-'0')
+			digits = true
+		}
+	}
+	if !digits {
+		return 0, fmt.Errorf("invalid cost %q", s)
+	}
+	return n, nil
+}
+
+// Key uniquely identifies an occasion, for de-duplicating results from overlapping queries
+func (o Occasion) Key() string {
+	return fmt.Sprintf("%d/%d/%d/%v", o.LocationID, o.ExaminationTypeID, o.Duration.Start.Unix(), o.Name)
+}
+
 // Occasions returns the available exam occasions for the specified parameters
 func (tc *TrafikverketClient) Occasions(body *OccasionBundlesRequest) (*[]Occasion, *http.Response, error) {
-	resp, res, err := tc.OccasionBundles(body)
+	return tc.OccasionsContext(context.Background(), body)
+}
+
+// OccasionsContext is like Occasions but with a context
+func (tc *TrafikverketClient) OccasionsContext(ctx context.Context, body *OccasionBundlesRequest) (*[]Occasion, *http.Response, error) {
+	ctx, span := tc.tracer.Start(ctx, "Occasions", trace.WithAttributes(
+		attribute.Int("trafikverket.licence_id", body.BookingSession.LicenceID),
+		attribute.Int("trafikverket.location_id", body.OccasionBundleQuery.LocationID),
+		attribute.Int("trafikverket.examination_type_id", body.OccasionBundleQuery.ExaminationTypeID),
+	))
+	defer span.End()
+
+	resp, res, err := tc.OccasionBundlesContext(ctx, body)
 	if err != nil {
-		return nil, res, err
+		return nil, res, endSpan(span, err)
 	}
 
 	// merge occasions
@@ -124,6 +209,31 @@ func (tc *TrafikverketClient) Occasions(body *OccasionBundlesRequest) (*[]Occasi
 			o = append(o, d.Occasions...)
 		}
 	}
+	span.SetAttributes(attribute.Int("trafikverket.results", len(o)))
 
 	return &o, res, nil
 }
+
+// Bundles returns the available occasion bundles for the specified parameters, keeping the occasions that
+// are booked together and the cost of each bundle
+func (tc *TrafikverketClient) Bundles(body *OccasionBundlesRequest) (*[]Bundle, *http.Response, error) {
+	return tc.BundlesContext(context.Background(), body)
+}
+
+// BundlesContext is like Bundles but with a context
+func (tc *TrafikverketClient) BundlesContext(ctx context.Context, body *OccasionBundlesRequest) (*[]Bundle, *http.Response, error) {
+	ctx, span := tc.tracer.Start(ctx, "Bundles", trace.WithAttributes(
+		attribute.Int("trafikverket.licence_id", body.BookingSession.LicenceID),
+		attribute.Int("trafikverket.location_id", body.OccasionBundleQuery.LocationID),
+		attribute.Int("trafikverket.examination_type_id", body.OccasionBundleQuery.ExaminationTypeID),
+	))
+	defer span.End()
+
+	resp, res, err := tc.OccasionBundlesContext(ctx, body)
+	if err != nil {
+		return nil, res, endSpan(span, err)
+	}
+	span.SetAttributes(attribute.Int("trafikverket.results", len(resp.Data)))
+
+	return &resp.Data, res, nil
+}
@@ -0,0 +1,124 @@
+// Copyright © 2017 Sebastian Mandrean <sebastian.mandrean@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package pkg
+
+import (
+	"context"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"iter"
+	"sort"
+	"time"
+)
+
+const (
+	// slotStep is how far past the last returned slot the next page starts, as slots start on whole minutes
+	slotStep = time.Minute
+	// emptyPageStep is how far the start date is advanced past a query returning no occasions before the
+	// API's window has been observed, as there is no last slot to advance past
+	emptyPageStep = 7 * 24 * time.Hour
+)
+
+// OccasionsBetween returns the unique occasions starting in [from, to) for the query in body, sorted by start.
+// The API only returns a limited window from the query's start date, so it is re-queried with the start date
+// advanced past the last returned occasion until to is reached. The StartDate of body is ignored.
+func OccasionsBetween(ctx context.Context, client Client, body *OccasionBundlesRequest, from, to time.Time) ([]Occasion, error) {
+	var os []Occasion
+	for o, err := range OccasionsSeq(ctx, client, body, from, to) {
+		if err != nil {
+			return nil, err
+		}
+		os = append(os, o)
+	}
+
+	sort.SliceStable(os, func(i, j int) bool {
+		return os[i].Duration.Start.Before(os[j].Duration.Start)
+	})
+
+	return os, nil
+}
+
+// OccasionsSeq is like OccasionsBetween but yields the occasions as they are fetched, page by page.
+// Paging stops as soon as the consumer breaks, and after yielding the first error.
+func OccasionsSeq(ctx context.Context, client Client, body *OccasionBundlesRequest, from, to time.Time) iter.Seq2[Occasion, error] {
+	return func(yield func(Occasion, error) bool) {
+		ctx, span := tracerFor(client).Start(ctx, "OccasionsBetween", trace.WithAttributes(
+			attribute.Int("trafikverket.licence_id", body.BookingSession.LicenceID),
+			attribute.Int("trafikverket.location_id", body.OccasionBundleQuery.LocationID),
+			attribute.Int("trafikverket.examination_type_id", body.OccasionBundleQuery.ExaminationTypeID),
+		))
+		defer span.End()
+
+		b := *body
+		seen := make(map[string]bool)
+		pages, results := 0, 0
+		defer func() {
+			span.SetAttributes(
+				attribute.Int("trafikverket.pages", pages),
+				attribute.Int("trafikverket.results", results),
+			)
+		}()
+
+		// window is the longest span between a page's start date and its last slot, stepped across empty
+		// pages so that they don't skip past occasions the API would have returned
+		var window time.Duration
+		for start := from; start.Before(to); {
+			b.OccasionBundleQuery.StartDate = start
+			os, _, err := client.OccasionsContext(ctx, &b)
+			if err != nil {
+				yield(Occasion{}, endSpan(span, err))
+				return
+			}
+			pages++
+
+			// windows of consecutive pages overlap, so skip what was already yielded
+			last := start
+			for _, o := range *os {
+				if o.Duration.Start.After(last) {
+					last = o.Duration.Start
+				}
+				if o.Duration.Start.Before(from) || !o.Duration.Start.Before(to) || seen[o.Key()] {
+					continue
+				}
+				seen[o.Key()] = true
+				results++
+				if !yield(o, nil) {
+					return
+				}
+			}
+
+			// the API may only consider the date, so make sure to always move forward
+			switch {
+			case len(*os) == 0 && window > 0:
+				start = start.Add(window)
+			case len(*os) == 0:
+				start = start.Add(emptyPageStep)
+			case last.After(start):
+				if d := last.Sub(start); d > window {
+					window = d
+				}
+				start = last.Add(slotStep)
+			default:
+				start = start.AddDate(0, 0, 1)
+			}
+		}
+	}
+}
@@ -0,0 +1,231 @@
+// Copyright © 2017 Sebastian Mandrean <sebastian.mandrean@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package pkg_test
+
+import (
+	"context"
+	"errors"
+	"github.com/mandrean/go-trafikverket/pkg"
+	"github.com/mandrean/go-trafikverket/pkg/trafikverkettest"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// drivingTests returns the driving tests at location in f starting in [from, to), sorted by start
+func drivingTests(f trafikverkettest.Fixtures, location int, from, to time.Time) []pkg.Occasion {
+	var os []pkg.Occasion
+	for _, o := range f.Occasions {
+		s := o.Duration.Start
+		if o.LocationID == location && o.ExaminationTypeID == trafikverkettest.DrivingExaminationTypeID && !s.Before(from) && s.Before(to) {
+			os = append(os, o)
+		}
+	}
+	return os
+}
+
+func drivingTestsRequest() *pkg.OccasionBundlesRequest {
+	return &pkg.OccasionBundlesRequest{
+		BookingSession:      pkg.BookingSession{SocialSecurityNumber: testSSN, LicenceID: pkg.DefaultLicenceID},
+		OccasionBundleQuery: pkg.OccasionBundleQuery{LocationID: stockholm, ExaminationTypeID: trafikverkettest.DrivingExaminationTypeID},
+	}
+}
+
+func TestOccasionsBetweenPages(t *testing.T) {
+	now := time.Now()
+	f := trafikverkettest.DefaultFixtures(now)
+	all := drivingTests(f, stockholm, now, now.AddDate(0, 0, 60))
+
+	// bounds falling exactly on occasions, which [from, to) includes and excludes respectively
+	first, last := all[3].Duration.Start, all[len(all)-3].Duration.Start
+
+	tests := []struct {
+		name     string
+		window   time.Duration
+		from, to time.Time
+		minPages int
+	}{
+		{"one page", 60 * 24 * time.Hour, now, now.AddDate(0, 0, 60), 1},
+		{"pages ending within a day", 36 * time.Hour, now, now.AddDate(0, 0, 60), 10},
+		{"pages of several days", 5 * 24 * time.Hour, now, now.AddDate(0, 0, 60), 5},
+		{"bounds on occasions", 5 * 24 * time.Hour, first, last, 5},
+		{"to before from", 5 * 24 * time.Hour, last, first, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := trafikverkettest.NewServer(f)
+			defer s.Close()
+			s.SetWindow(tt.window)
+
+			os, err := pkg.OccasionsBetween(context.Background(), s.Client(), drivingTestsRequest(), tt.from, tt.to)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			want := drivingTests(f, stockholm, tt.from, tt.to)
+			if len(os) != len(want) {
+				t.Fatalf("got %d occasions, want %d", len(os), len(want))
+			}
+			for i := range want {
+				if !os[i].Duration.Start.Equal(want[i].Duration.Start) {
+					t.Errorf("occasion %d starts at %v, want %v", i, os[i].Duration.Start, want[i].Duration.Start)
+				}
+			}
+			if n := s.Requests("occasion-bundles"); n < tt.minPages {
+				t.Errorf("got %d pages, want at least %d", n, tt.minPages)
+			}
+		})
+	}
+}
+
+func TestOccasionsSeqStopsPaging(t *testing.T) {
+	now := time.Now()
+	s := trafikverkettest.NewServer(trafikverkettest.DefaultFixtures(now))
+	defer s.Close()
+	s.SetWindow(5 * 24 * time.Hour)
+
+	n := 0
+	for _, err := range pkg.OccasionsSeq(context.Background(), s.Client(), drivingTestsRequest(), now, now.AddDate(0, 0, 60)) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n++; n == 3 {
+			break
+		}
+	}
+	if got := s.Requests("occasion-bundles"); got != 1 {
+		t.Errorf("got %d pages after breaking on the first, want 1", got)
+	}
+}
+
+// failingClient fails the nth call to OccasionsContext
+type failingClient struct {
+	pkg.Client
+	n, calls int
+}
+
+func (c *failingClient) OccasionsContext(ctx context.Context, body *pkg.OccasionBundlesRequest) (*[]pkg.Occasion, *http.Response, error) {
+	if c.calls++; c.calls == c.n {
+		return nil, nil, &pkg.StatusError{StatusCode: http.StatusBadGateway, Status: http.StatusText(http.StatusBadGateway)}
+	}
+	return c.Client.OccasionsContext(ctx, body)
+}
+
+func TestOccasionsSeqError(t *testing.T) {
+	now := time.Now()
+	sc := trafikverkettest.NewStaticClient(trafikverkettest.DefaultFixtures(now))
+	sc.SetWindow(5 * 24 * time.Hour)
+	c := &failingClient{Client: sc, n: 2}
+
+	var errs []error
+	n := 0
+	for _, err := range pkg.OccasionsSeq(context.Background(), c, drivingTestsRequest(), now, now.AddDate(0, 0, 60)) {
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if len(errs) > 0 {
+			t.Fatal("got an occasion after the error")
+		}
+		n++
+	}
+
+	var se *pkg.StatusError
+	if len(errs) != 1 || !errors.As(errs[0], &se) || se.StatusCode != http.StatusBadGateway {
+		t.Errorf("got errors %v, want a single 502", errs)
+	}
+	if n == 0 {
+		t.Error("got no occasions from the first page")
+	}
+	if c.calls != 2 {
+		t.Errorf("got %d pages, want paging to stop at the failing second", c.calls)
+	}
+
+	c.calls = 0
+	if _, err := pkg.OccasionsBetween(context.Background(), c, drivingTestsRequest(), now, now.AddDate(0, 0, 60)); err == nil {
+		t.Error("OccasionsBetween returned no error")
+	}
+}
+
+// pagingClient records the start date of every page and the last slot returned on it
+type pagingClient struct {
+	pkg.Client
+	starts, lasts []time.Time
+}
+
+func (c *pagingClient) OccasionsContext(ctx context.Context, body *pkg.OccasionBundlesRequest) (*[]pkg.Occasion, *http.Response, error) {
+	os, res, err := c.Client.OccasionsContext(ctx, body)
+	if err == nil {
+		var last time.Time
+		for _, o := range *os {
+			if o.Duration.Start.After(last) {
+				last = o.Duration.Start
+			}
+		}
+		c.starts, c.lasts = append(c.starts, body.OccasionBundleQuery.StartDate), append(c.lasts, last)
+	}
+	return os, res, err
+}
+
+func TestOccasionsSeqPagesPastLastSlot(t *testing.T) {
+	now := time.Now()
+	sc := trafikverkettest.NewStaticClient(trafikverkettest.DefaultFixtures(now))
+	sc.SetWindow(3 * 24 * time.Hour)
+	c := &pagingClient{Client: sc}
+
+	if _, err := pkg.OccasionsBetween(context.Background(), c, drivingTestsRequest(), now, now.AddDate(0, 0, 30)); err != nil {
+		t.Fatal(err)
+	}
+	for i := 1; i < len(c.starts); i++ {
+		if !c.lasts[i-1].IsZero() && !c.starts[i].After(c.lasts[i-1]) {
+			t.Errorf("page %d starts at %v, want after the last slot of the previous page at %v", i, c.starts[i], c.lasts[i-1])
+		}
+	}
+}
+
+func TestOccasionsBetweenEmptyLocation(t *testing.T) {
+	now := time.Now()
+	f := trafikverkettest.DefaultFixtures(now)
+	var os []pkg.Occasion
+	for _, o := range f.Occasions {
+		if o.LocationID != stockholm {
+			os = append(os, o)
+		}
+	}
+	f.Occasions = os
+
+	s := trafikverkettest.NewServer(f)
+	defer s.Close()
+	s.SetWindow(5 * 24 * time.Hour)
+
+	got, err := pkg.OccasionsBetween(context.Background(), s.Client(), drivingTestsRequest(), now, now.AddDate(0, 3, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 0 {
+		t.Errorf("got %d occasions, want none", len(got))
+	}
+	// a page per emptyPageStep of a week
+	if n := s.Requests("occasion-bundles"); n > 14 {
+		t.Errorf("got %d requests for three months without occasions, want at most 14", n)
+	}
+}
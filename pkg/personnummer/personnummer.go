@@ -0,0 +1,178 @@
+// Copyright © 2017 Sebastian Mandrean <sebastian.mandrean@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package personnummer parses, validates and formats Swedish personal identity numbers
+// (personnummer) and coordination numbers (samordningsnummer).
+package personnummer
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInvalidFormat   = errors.New("personnummer: invalid format, expected YYMMDD-NNNN or YYYYMMDD-NNNN")
+	ErrInvalidDate     = errors.New("personnummer: invalid date of birth")
+	ErrInvalidChecksum = errors.New("personnummer: invalid checksum")
+
+	pattern = regexp.MustCompile(`^(\d{2})?(\d{2})(\d{2})(\d{2})([-+]?)(\d{3})(\d)$`)
+
+	// now is overridable for deterministic century inference
+	now = time.Now
+)
+
+type (
+	// Personnummer is a parsed personnummer or samordningsnummer
+	Personnummer struct {
+		// BirthDate is the date of birth. For samordningsnummer, the day is the actual day (without the +60).
+		BirthDate time.Time
+		// Serial is the three digit birth number
+		Serial string
+		// Check is the Luhn check digit
+		Check int
+		// Coordination is true for samordningsnummer
+		Coordination bool
+	}
+)
+
+// Parse parses s in any of the forms YYMMDD-NNNN, YYMMDD+NNNN, YYMMDDNNNN, YYYYMMDD-NNNN or YYYYMMDDNNNN.
+// For 10 digit forms the century is inferred, where a + separator means the person is 100 years or older.
+func Parse(s string) (Personnummer, error) {
+	return ParseAt(s, now())
+}
+
+// ParseAt is like Parse but infers the century relative to t
+func ParseAt(s string, t time.Time) (Personnummer, error) {
+	m := pattern.FindStringSubmatch(strings.TrimSpace(s))
+	if m == nil {
+		return Personnummer{}, ErrInvalidFormat
+	}
+	century, yy, mm, dd, sep, serial, check := m[1], m[2], m[3], m[4], m[5], m[6], m[7]
+
+	// verify checksum over the 10 digit form
+	c, _ := strconv.Atoi(check)
+	if Luhn(yy+mm+dd+serial) != c {
+		return Personnummer{}, ErrInvalidChecksum
+	}
+
+	// samordningsnummer have 60 added to the day
+	month, _ := strconv.Atoi(mm)
+	day, _ := strconv.Atoi(dd)
+	coordination := day > 60
+	if coordination {
+		day -= 60
+	}
+
+	// infer century
+	y, _ := strconv.Atoi(yy)
+	var year int
+	if century != "" {
+		cc, _ := strconv.Atoi(century)
+		year = cc*100 + y
+	} else {
+		// start from the latest year ending in yy and step back a century while the date of birth is after t,
+		// or with a + separator, while the person would be younger than 100 at t
+		year = t.Year() - (t.Year()-y)%100
+		for {
+			d := time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.UTC)
+			if sep == "+" {
+				d = d.AddDate(100, 0, 0)
+			}
+			if !d.After(t) {
+				break
+			}
+			year -= 100
+		}
+	}
+
+	d := time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.UTC)
+	if d.Year() != year || int(d.Month()) != month || d.Day() != day {
+		return Personnummer{}, ErrInvalidDate
+	}
+
+	return Personnummer{
+		BirthDate:    d,
+		Serial:       serial,
+		Check:        c,
+		Coordination: coordination,
+	}, nil
+}
+
+// Valid reports whether s is a valid personnummer or samordningsnummer
+func Valid(s string) bool {
+	_, err := Parse(s)
+	return err == nil
+}
+
+// Normalise parses s and returns it in canonical form
+func Normalise(s string) (string, error) {
+	p, err := Parse(s)
+	if err != nil {
+		return "", err
+	}
+	return p.String(), nil
+}
+
+// Luhn returns the Luhn check digit for digits
+func Luhn(digits string) int {
+	sum := 0
+	for i, r := range digits {
+		d := int(r - '0')
+		if i%2 == 0 {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+	}
+	return (10 - sum%10) % 10
+}
+
+// String returns the canonical 12 digit form YYYYMMDDNNNN
+func (p Personnummer) String() string {
+	return fmt.Sprintf("%04d%02d%02d%v%d", p.BirthDate.Year(), int(p.BirthDate.Month()), p.day(), p.Serial, p.Check)
+}
+
+// Long returns the 12 digit form with separator, YYYYMMDD-NNNN
+func (p Personnummer) Long() string {
+	return fmt.Sprintf("%04d%02d%02d-%v%d", p.BirthDate.Year(), int(p.BirthDate.Month()), p.day(), p.Serial, p.Check)
+}
+
+// Short returns the 10 digit form with separator, YYMMDD-NNNN, or YYMMDD+NNNN if the person is 100 years or older
+func (p Personnummer) Short() string {
+	sep := "-"
+	if !p.BirthDate.AddDate(100, 0, 0).After(now()) {
+		sep = "+"
+	}
+	return fmt.Sprintf("%02d%02d%02d%v%v%d", p.BirthDate.Year()%100, int(p.BirthDate.Month()), p.day(), sep, p.Serial, p.Check)
+}
+
+// day returns the day as written in the number, i.e. with 60 added for samordningsnummer
+func (p Personnummer) day() int {
+	if p.Coordination {
+		return p.BirthDate.Day() + 60
+	}
+	return p.BirthDate.Day()
+}
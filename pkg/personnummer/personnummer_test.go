@@ -0,0 +1,141 @@
+// Copyright © 2017 Sebastian Mandrean <sebastian.mandrean@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package personnummer
+
+import (
+	"testing"
+	"time"
+)
+
+// at is the time numbers are parsed relative to in the tests
+var at = time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+
+func TestLuhn(t *testing.T) {
+	for _, tt := range []struct {
+		digits string
+		want   int
+	}{
+		{"900101001", 7},
+		{"811228987", 4},
+		{"701063232", 6},
+		{"261231000", 9},
+		{"000000000", 0},
+	} {
+		if got := Luhn(tt.digits); got != tt.want {
+			t.Errorf("Luhn(%q) = %d, want %d", tt.digits, got, tt.want)
+		}
+	}
+}
+
+func TestParseAt(t *testing.T) {
+	for _, tt := range []struct {
+		s            string
+		want         string
+		coordination bool
+		err          error
+	}{
+		// 12 digit forms
+		{s: "199001010017", want: "1990-01-01"},
+		{s: "19900101-0017", want: "1990-01-01"},
+		{s: " 19811228-9874 ", want: "1981-12-28"},
+
+		// 10 digit forms
+		{s: "900101-0017", want: "1990-01-01"},
+		{s: "9001010017", want: "1990-01-01"},
+		{s: "811228-9874", want: "1981-12-28"},
+
+		// the century of 10 digit forms depends on the whole date, not just the year
+		{s: "261019-0007", want: "2026-10-19"},
+		{s: "261020-0004", want: "1926-10-20"},
+		{s: "261231-0009", want: "1926-12-31"},
+
+		// + means 100 years or older
+		{s: "900101+0017", want: "1890-01-01"},
+		{s: "261019+0007", want: "1926-10-19"},
+		{s: "261020+0004", want: "1826-10-20"},
+		{s: "000229-0005", want: "2000-02-29"},
+		{s: "000229+0005", err: ErrInvalidDate},
+
+		// samordningsnummer have 60 added to the day
+		{s: "701063-2326", want: "1970-10-03", coordination: true},
+		{s: "197010632326", want: "1970-10-03", coordination: true},
+
+		// invalid numbers
+		{s: "199001010018", err: ErrInvalidChecksum},
+		{s: "900101-0018", err: ErrInvalidChecksum},
+		{s: "900101+0018", err: ErrInvalidChecksum},
+		{s: "19900101001", err: ErrInvalidFormat},
+		{s: "1990-01-01-0017", err: ErrInvalidFormat},
+		{s: "", err: ErrInvalidFormat},
+	} {
+		p, err := ParseAt(tt.s, at)
+		if err != tt.err {
+			t.Errorf("ParseAt(%q) error = %v, want %v", tt.s, err, tt.err)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if got := p.BirthDate.Format("2006-01-02"); got != tt.want {
+			t.Errorf("ParseAt(%q) = %v, want %v", tt.s, got, tt.want)
+		}
+		if p.Coordination != tt.coordination {
+			t.Errorf("ParseAt(%q) coordination = %v, want %v", tt.s, p.Coordination, tt.coordination)
+		}
+	}
+}
+
+func TestNormalise(t *testing.T) {
+	defer func(f func() time.Time) { now = f }(now)
+	now = func() time.Time { return at }
+
+	for _, tt := range []struct {
+		s     string
+		want  string
+		short string
+	}{
+		{"900101-0017", "199001010017", "900101-0017"},
+		{"19900101-0017", "199001010017", "900101-0017"},
+		{"9001010017", "199001010017", "900101-0017"},
+		{"701063-2326", "197010632326", "701063-2326"},
+		{"261231-0009", "192612310009", "261231-0009"},
+		{"261019+0007", "192610190007", "261019+0007"},
+		{"900101+0017", "189001010017", "900101+0017"},
+	} {
+		got, err := Normalise(tt.s)
+		if err != nil {
+			t.Errorf("Normalise(%q) error = %v", tt.s, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("Normalise(%q) = %v, want %v", tt.s, got, tt.want)
+		}
+
+		p, _ := Parse(tt.s)
+		if got := p.Short(); got != tt.short {
+			t.Errorf("Parse(%q).Short() = %v, want %v", tt.s, got, tt.short)
+		}
+	}
+
+	if _, err := Normalise("199001010018"); err != ErrInvalidChecksum {
+		t.Errorf("Normalise of an invalid checksum: error = %v, want %v", err, ErrInvalidChecksum)
+	}
+}
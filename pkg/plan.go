@@ -0,0 +1,252 @@
+// Copyright © 2017 Sebastian Mandrean <sebastian.mandrean@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package pkg
+
+import (
+	"context"
+	"errors"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// theoryPrefix and drivingPrefix start the names of the theory and driving test examination types,
+	// e.g. "Kunskapsprov B" and "Körprov B"
+	theoryPrefix  = "kunskapsprov"
+	drivingPrefix = "körprov"
+)
+
+type (
+	// PlanOptions configures which theory and driving test pairs Plan proposes
+	PlanOptions struct {
+		LocationIDs []int
+		// TheoryExaminationTypeID and DrivingExaminationTypeID default to the theory and driving tests
+		// of the booking session's licence, see TestExaminationTypes
+		TheoryExaminationTypeID  int
+		DrivingExaminationTypeID int
+		// MinGap and MaxGap bound the time between the end of the theory test and the start of the
+		// driving test. A MaxGap of 0 means no upper bound.
+		MinGap time.Duration
+		MaxGap time.Duration
+		// MaxDistance is how far apart in kilometres the theory and driving test locations may be.
+		// With 0 both tests are taken at the same location.
+		MaxDistance float64
+		// Until pages through the calendar from the query's start date until this time, see OccasionsBetween.
+		// If zero, only the window returned for the start date is searched.
+		Until       time.Time
+		Parallelism int
+	}
+
+	// Pair is a theory test followed by a driving test
+	Pair struct {
+		Theory  Occasion      `yaml:"theory"`
+		Driving Occasion      `yaml:"driving"`
+		Gap     time.Duration `yaml:"gap"`
+		// Distance is between the theory and driving test locations, in kilometres
+		Distance float64 `yaml:"distance"`
+	}
+)
+
+// Completion returns when the driving test, and thereby the pair, is completed
+func (p Pair) Completion() time.Time {
+	return p.Driving.Duration.End
+}
+
+// Plan proposes theory and driving test pairs at the locations in opts, ranked by earliest completion.
+// For each theory test only the earliest valid driving test at each location is proposed. body is used as
+// a template for every query, with its LocationID and ExaminationTypeID overridden.
+func Plan(ctx context.Context, client Client, body *OccasionBundlesRequest, opts PlanOptions) ([]Pair, error) {
+	ctx, span := tracerFor(client).Start(ctx, "Plan", trace.WithAttributes(
+		attribute.IntSlice("trafikverket.location_ids", opts.LocationIDs),
+	))
+	defer span.End()
+
+	if len(opts.LocationIDs) == 0 {
+		return nil, endSpan(span, errors.New("no locations specified"))
+	}
+	if opts.Parallelism < 1 {
+		opts.Parallelism = 1
+	}
+
+	// look up the examination types of the licence, and the location coordinates for pairing nearby locations
+	coordinates := make(map[int]Coordinates)
+	if opts.TheoryExaminationTypeID == 0 || opts.DrivingExaminationTypeID == 0 || opts.MaxDistance > 0 {
+		info, _, err := client.SearchInformationContext(ctx, &SearchInformationRequest{BookingSession: body.BookingSession})
+		if err != nil {
+			return nil, endSpan(span, err)
+		}
+		if opts.TheoryExaminationTypeID == 0 || opts.DrivingExaminationTypeID == 0 {
+			theory, driving, err := TestExaminationTypes(info)
+			if err != nil {
+				return nil, endSpan(span, err)
+			}
+			if opts.TheoryExaminationTypeID == 0 {
+				opts.TheoryExaminationTypeID = theory
+			}
+			if opts.DrivingExaminationTypeID == 0 {
+				opts.DrivingExaminationTypeID = driving
+			}
+		}
+		for _, l := range info.Data.Locations {
+			coordinates[int(l.ID)] = l.Coordinates
+		}
+	}
+
+	theory, err := planOccasions(ctx, client, *body, opts, opts.TheoryExaminationTypeID)
+	if err != nil {
+		return nil, endSpan(span, err)
+	}
+	driving, err := planOccasions(ctx, client, *body, opts, opts.DrivingExaminationTypeID)
+	if err != nil {
+		return nil, endSpan(span, err)
+	}
+
+	var pairs []Pair
+	for _, lt := range opts.LocationIDs {
+		for _, ld := range opts.LocationIDs {
+			distance := 0.0
+			if ld != lt {
+				a, aok := coordinates[lt]
+				b, bok := coordinates[ld]
+				if !aok || !bok {
+					continue
+				}
+				if distance = a.Distance(b); distance > opts.MaxDistance {
+					continue
+				}
+			}
+
+			for _, t := range theory[lt] {
+				earliest := t.Duration.End.Add(opts.MinGap)
+				i := sort.Search(len(driving[ld]), func(i int) bool {
+					return !driving[ld][i].Duration.Start.Before(earliest)
+				})
+				if i == len(driving[ld]) {
+					continue
+				}
+				d := driving[ld][i]
+				gap := d.Duration.Start.Sub(t.Duration.End)
+				if opts.MaxGap > 0 && gap > opts.MaxGap {
+					continue
+				}
+				pairs = append(pairs, Pair{Theory: t, Driving: d, Gap: gap, Distance: distance})
+			}
+		}
+	}
+
+	sort.SliceStable(pairs, func(i, j int) bool {
+		if !pairs[i].Completion().Equal(pairs[j].Completion()) {
+			return pairs[i].Completion().Before(pairs[j].Completion())
+		}
+		return pairs[i].Theory.Duration.Start.Before(pairs[j].Theory.Duration.Start)
+	})
+	span.SetAttributes(attribute.Int("trafikverket.results", len(pairs)))
+
+	return pairs, nil
+}
+
+// TestExaminationTypes returns the IDs of the theory test (kunskapsprov) and the driving test (körprov) among the
+// examination types in info, the search information of a licence, by their names. If the licence has several of
+// either, the first is returned. A *ValidationError is returned if it has none.
+func TestExaminationTypes(info *SearchInformationResponse) (theory int, driving int, err error) {
+	var valid []string
+	for _, e := range info.Data.ExaminationTypes {
+		name := strings.ToLower(e.Name)
+		switch {
+		case theory == 0 && strings.HasPrefix(name, theoryPrefix):
+			theory = int(e.ID)
+		case driving == 0 && strings.HasPrefix(name, drivingPrefix):
+			driving = int(e.ID)
+		}
+		valid = append(valid, option(int(e.ID), e.Name))
+	}
+
+	var ps []Problem
+	if theory == 0 {
+		ps = append(ps, Problem{Field: "theoryExaminationTypeId", Message: "the licence has no theory test, valid: " + strings.Join(valid, ", ")})
+	}
+	if driving == 0 {
+		ps = append(ps, Problem{Field: "drivingExaminationTypeId", Message: "the licence has no driving test, valid: " + strings.Join(valid, ", ")})
+	}
+	if len(ps) > 0 {
+		return 0, 0, &ValidationError{Problems: ps}
+	}
+	return theory, driving, nil
+}
+
+// planOccasions fetches the occasions of the examination type at each location, sorted by start
+func planOccasions(ctx context.Context, client Client, body OccasionBundlesRequest, opts PlanOptions, examinationTypeID int) (map[int][]Occasion, error) {
+	body.BookingSession.ExaminationTypeID = examinationTypeID
+	body.OccasionBundleQuery.ExaminationTypeID = examinationTypeID
+
+	var (
+		mu     sync.Mutex
+		result = make(map[int][]Occasion)
+		errs   []error
+	)
+	sem := make(chan struct{}, opts.Parallelism)
+	var wg sync.WaitGroup
+	for _, l := range opts.LocationIDs {
+		wg.Add(1)
+		go func(body OccasionBundlesRequest, l int) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			body.OccasionBundleQuery.LocationID = l
+			var os []Occasion
+			var err error
+			if opts.Until.IsZero() {
+				var res *[]Occasion
+				if res, _, err = client.OccasionsContext(ctx, &body); err == nil {
+					os = *res
+				}
+			} else {
+				from := body.OccasionBundleQuery.StartDate
+				if from.IsZero() {
+					from = time.Now()
+				}
+				os, err = OccasionsBetween(ctx, client, &body, from, opts.Until)
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				errs = append(errs, err)
+				return
+			}
+			sort.SliceStable(os, func(i, j int) bool {
+				return os[i].Duration.Start.Before(os[j].Duration.Start)
+			})
+			result[l] = os
+		}(body, l)
+	}
+	wg.Wait()
+
+	if len(errs) > 0 {
+		return nil, errs[0]
+	}
+	return result, nil
+}
@@ -0,0 +1,111 @@
+// Copyright © 2017 Sebastian Mandrean <sebastian.mandrean@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package pkg_test
+
+import (
+	"context"
+	"errors"
+	"github.com/mandrean/go-trafikverket/pkg"
+	"github.com/mandrean/go-trafikverket/pkg/trafikverkettest"
+	"testing"
+	"time"
+)
+
+// relabel gives the examination types of f other IDs and names, like those of another licence
+func relabel(f trafikverkettest.Fixtures, theoryID int, theory string, drivingID int, driving string) trafikverkettest.Fixtures {
+	ids := map[int]int{trafikverkettest.TheoryExaminationTypeID: theoryID, trafikverkettest.DrivingExaminationTypeID: drivingID}
+	names := map[int]string{theoryID: theory, drivingID: driving}
+
+	f.ExaminationTypes = []pkg.ExaminationType{
+		{ID: uint64(drivingID), Name: driving},
+		{ID: uint64(theoryID), Name: theory},
+	}
+	os := make([]pkg.Occasion, len(f.Occasions))
+	for i, o := range f.Occasions {
+		o.ExaminationTypeID = ids[o.ExaminationTypeID]
+		o.Name = names[o.ExaminationTypeID]
+		os[i] = o
+	}
+	f.Occasions = os
+	return f
+}
+
+func TestPlan(t *testing.T) {
+	body := &pkg.OccasionBundlesRequest{
+		BookingSession: pkg.BookingSession{SocialSecurityNumber: testSSN, LicenceID: pkg.DefaultLicenceID},
+	}
+	opts := pkg.PlanOptions{LocationIDs: []int{1000140}, MinGap: 24 * time.Hour}
+
+	for _, tt := range []struct {
+		name    string
+		f       trafikverkettest.Fixtures
+		theory  string
+		driving string
+	}{
+		{"licence B", trafikverkettest.DefaultFixtures(time.Now()), "Kunskapsprov B", "Körprov B"},
+		{"other IDs", relabel(trafikverkettest.DefaultFixtures(time.Now()), 7, "Kunskapsprov C", 8, "Körprov C"), "Kunskapsprov C", "Körprov C"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			ps, err := pkg.Plan(context.Background(), trafikverkettest.NewStaticClient(tt.f), body, opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(ps) == 0 {
+				t.Fatal("no pairs")
+			}
+			for i, p := range ps {
+				if p.Theory.Name != tt.theory || p.Driving.Name != tt.driving {
+					t.Fatalf("pair %d is %v followed by %v, want %v followed by %v", i, p.Theory.Name, p.Driving.Name, tt.theory, tt.driving)
+				}
+				if p.Gap < opts.MinGap || !p.Driving.Duration.Start.Equal(p.Theory.Duration.End.Add(p.Gap)) {
+					t.Errorf("pair %d has a gap of %v, want at least %v", i, p.Gap, opts.MinGap)
+				}
+				if i > 0 && p.Completion().Before(ps[i-1].Completion()) {
+					t.Errorf("pair %d completes before pair %d", i, i-1)
+				}
+			}
+		})
+	}
+}
+
+func TestPlanUnknownExaminationTypes(t *testing.T) {
+	f := relabel(trafikverkettest.DefaultFixtures(time.Now()), 7, "Teoriprov", 8, "Praktiskt prov")
+	body := &pkg.OccasionBundlesRequest{
+		BookingSession: pkg.BookingSession{SocialSecurityNumber: testSSN, LicenceID: pkg.DefaultLicenceID},
+	}
+	c := trafikverkettest.NewStaticClient(f)
+
+	_, err := pkg.Plan(context.Background(), c, body, pkg.PlanOptions{LocationIDs: []int{1000140}})
+	var ve *pkg.ValidationError
+	if !errors.As(err, &ve) || len(ve.Problems) != 2 {
+		t.Fatalf("err = %v, want a *ValidationError for both examination types", err)
+	}
+
+	// explicit IDs need no lookup
+	ps, err := pkg.Plan(context.Background(), c, body, pkg.PlanOptions{
+		LocationIDs:              []int{1000140},
+		TheoryExaminationTypeID:  7,
+		DrivingExaminationTypeID: 8,
+	})
+	if err != nil || len(ps) == 0 {
+		t.Fatalf("Plan with explicit examination types = %d pairs, %v", len(ps), err)
+	}
+}
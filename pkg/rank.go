@@ -0,0 +1,181 @@
+// Copyright © 2017 Sebastian Mandrean <sebastian.mandrean@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package pkg
+
+import (
+	"sort"
+	"time"
+)
+
+type (
+	// RankWeights weigh the criteria of a ranking against each other. A weight of 0 ignores the criterion.
+	RankWeights struct {
+		// Soon favours occasions starting earlier
+		Soon float64 `yaml:"soon"`
+		// Distance favours occasions closer to RankOptions.Home, and is ignored without it
+		Distance float64 `yaml:"distance"`
+		// Cost favours cheaper occasions, and those without an increased fee
+		Cost float64 `yaml:"cost"`
+		// Preferred favours occasions on RankOptions.Weekdays and between RankOptions.After and Before
+		Preferred float64 `yaml:"preferred"`
+	}
+
+	// RankOptions configures Rank
+	RankOptions struct {
+		Weights RankWeights
+		// Home is where the distance to each occasion's location is measured from
+		Home Coordinates
+		// Locations provides the coordinates of the occasions' locations
+		Locations []Location
+		// Weekdays are the preferred days, any day if empty
+		Weekdays []time.Weekday
+		// After and Before bound the preferred start time of day, as the time since midnight.
+		// Before 0 means no upper bound.
+		After  time.Duration
+		Before time.Duration
+	}
+
+	// RankedOccasion is an occasion with its score in [0, 1], higher being better
+	RankedOccasion struct {
+		Occasion `yaml:",inline"`
+		Score    float64 `yaml:"score"`
+		// Distance from RankOptions.Home in kilometres, if known
+		Distance float64 `yaml:"distance,omitempty"`
+	}
+)
+
+// DefaultRankWeights weigh every criterion equally
+var DefaultRankWeights = RankWeights{Soon: 1, Distance: 1, Cost: 1, Preferred: 1}
+
+// Rank scores the occasions by the weighted average of how soon they start, their distance from home, their
+// cost and whether they are at a preferred time, and returns them best first. Each criterion is scored
+// relative to the best and worst of os.
+func Rank(os []Occasion, opts RankOptions) []RankedOccasion {
+	ranked := make([]RankedOccasion, len(os))
+	if len(os) == 0 {
+		return ranked
+	}
+
+	coordinates := make(map[int]Coordinates)
+	for _, l := range opts.Locations {
+		coordinates[int(l.ID)] = l.Coordinates
+	}
+
+	starts := make([]float64, len(os))
+	distances := make([]float64, len(os))
+	costs := make([]float64, len(os))
+	hasDistance := make([]bool, len(os))
+	hasCost := make([]bool, len(os))
+	for i, o := range os {
+		ranked[i].Occasion = o
+		starts[i] = float64(o.Duration.Start.Unix())
+		if c, ok := coordinates[o.LocationID]; ok && !opts.Home.IsZero() && !c.IsZero() {
+			ranked[i].Distance = opts.Home.Distance(c)
+			distances[i] = ranked[i].Distance
+			hasDistance[i] = true
+		}
+		if c, err := ParseCost(o.Cost); err == nil {
+			costs[i] = float64(c)
+			hasCost[i] = true
+		}
+	}
+
+	soon := lowerIsBetter(starts, nil)
+	near := lowerIsBetter(distances, hasDistance)
+	cheap := lowerIsBetter(costs, hasCost)
+	w := opts.Weights
+	if opts.Home.IsZero() {
+		// there is nothing to measure the distance from
+		w.Distance = 0
+	}
+	total := w.Soon + w.Distance + w.Cost + w.Preferred
+	for i, o := range os {
+		if o.IncreasedFee {
+			cheap[i] = 0
+		}
+		if total > 0 {
+			ranked[i].Score = (w.Soon*soon[i] + w.Distance*near[i] + w.Cost*cheap[i] + w.Preferred*opts.preferred(o)) / total
+		}
+	}
+
+	sort.SliceStable(ranked, func(i, j int) bool {
+		if ranked[i].Score != ranked[j].Score {
+			return ranked[i].Score > ranked[j].Score
+		}
+		return ranked[i].Duration.Start.Before(ranked[j].Duration.Start)
+	})
+
+	return ranked
+}
+
+// preferred scores how well o matches the preferred weekdays and times of day, in [0, 1]
+func (opts RankOptions) preferred(o Occasion) float64 {
+	start := o.Duration.Start
+	score := 0.0
+
+	day := len(opts.Weekdays) == 0
+	for _, d := range opts.Weekdays {
+		if start.Weekday() == d {
+			day = true
+		}
+	}
+	if day {
+		score += 0.5
+	}
+
+	y, m, d := start.Date()
+	tod := start.Sub(time.Date(y, m, d, 0, 0, 0, 0, start.Location()))
+	if tod >= opts.After && (opts.Before == 0 || tod < opts.Before) {
+		score += 0.5
+	}
+
+	return score
+}
+
+// lowerIsBetter scores each value in [0, 1] relative to the lowest (1) and highest (0) of vs.
+// Values not marked as known score 0, unless known is nil.
+func lowerIsBetter(vs []float64, known []bool) []float64 {
+	scores := make([]float64, len(vs))
+	min, max, found := 0.0, 0.0, false
+	for i, v := range vs {
+		if known != nil && !known[i] {
+			continue
+		}
+		if !found || v < min {
+			min = v
+		}
+		if !found || v > max {
+			max = v
+		}
+		found = true
+	}
+	for i, v := range vs {
+		switch {
+		case known != nil && !known[i]:
+			scores[i] = 0
+		case max == min:
+			scores[i] = 1
+		default:
+			scores[i] = (max - v) / (max - min)
+		}
+	}
+	return scores
+}
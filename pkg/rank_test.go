@@ -0,0 +1,128 @@
+// Copyright © 2017 Sebastian Mandrean <sebastian.mandrean@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package pkg_test
+
+import (
+	"github.com/mandrean/go-trafikverket/pkg"
+	"testing"
+	"time"
+)
+
+// rankOccasion returns an occasion named name at location, starting at hour on the day offset days from monday
+func rankOccasion(name string, location int, days int, hour int, cost string) pkg.Occasion {
+	monday := time.Date(2030, time.January, 7, 0, 0, 0, 0, time.UTC)
+	var o pkg.Occasion
+	o.Name = name
+	o.LocationID = location
+	o.Duration.Start = monday.AddDate(0, 0, days).Add(time.Duration(hour) * time.Hour)
+	o.Cost = cost
+	return o
+}
+
+func TestRank(t *testing.T) {
+	var near, far pkg.Location
+	near.ID, near.Coordinates = 1, pkg.Coordinates{Latitude: 59.33, Longitude: 18.07}
+	far.ID, far.Coordinates = 2, pkg.Coordinates{Latitude: 55.60, Longitude: 13.00}
+	home := pkg.Coordinates{Latitude: 59.35, Longitude: 18.00}
+
+	expensive := rankOccasion("expensive", 1, 0, 8, "1000 kr")
+	expensive.IncreasedFee = true
+
+	tests := []struct {
+		name string
+		os   []pkg.Occasion
+		opts pkg.RankOptions
+		want []string
+	}{
+		{
+			name: "sooner first",
+			os:   []pkg.Occasion{rankOccasion("late", 1, 2, 8, "800 kr"), rankOccasion("early", 1, 0, 8, "800 kr"), rankOccasion("middle", 1, 1, 8, "800 kr")},
+			opts: pkg.RankOptions{Weights: pkg.RankWeights{Soon: 1}},
+			want: []string{"early", "middle", "late"},
+		},
+		{
+			name: "cheaper first, increased fee last",
+			os:   []pkg.Occasion{expensive, rankOccasion("cheap", 1, 2, 8, "325 kr"), rankOccasion("unknown", 1, 1, 8, ""), rankOccasion("normal", 1, 0, 8, "800 kr")},
+			opts: pkg.RankOptions{Weights: pkg.RankWeights{Cost: 1}},
+			want: []string{"cheap", "normal", "expensive", "unknown"},
+		},
+		{
+			name: "nearer first",
+			os:   []pkg.Occasion{rankOccasion("far", 2, 0, 8, "800 kr"), rankOccasion("unknown", 3, 0, 8, "800 kr"), rankOccasion("near", 1, 1, 8, "800 kr")},
+			opts: pkg.RankOptions{Weights: pkg.RankWeights{Distance: 1}, Home: home, Locations: []pkg.Location{near, far}},
+			want: []string{"near", "far", "unknown"},
+		},
+		{
+			name: "distance ignored without home",
+			os:   []pkg.Occasion{rankOccasion("later", 1, 1, 8, "800 kr"), rankOccasion("sooner", 2, 0, 8, "800 kr")},
+			opts: pkg.RankOptions{Weights: pkg.RankWeights{Distance: 1}, Locations: []pkg.Location{near, far}},
+			want: []string{"sooner", "later"},
+		},
+		{
+			name: "preferred weekday and time of day first",
+			os:   []pkg.Occasion{rankOccasion("monday morning", 1, 0, 8, "800 kr"), rankOccasion("tuesday afternoon", 1, 1, 14, "800 kr"), rankOccasion("monday afternoon", 1, 0, 14, "800 kr")},
+			opts: pkg.RankOptions{Weights: pkg.RankWeights{Preferred: 1}, Weekdays: []time.Weekday{time.Tuesday}, After: 12 * time.Hour},
+			want: []string{"tuesday afternoon", "monday afternoon", "monday morning"},
+		},
+		{
+			name: "before bounds the time of day",
+			os:   []pkg.Occasion{rankOccasion("evening", 1, 0, 18, "800 kr"), rankOccasion("afternoon", 1, 1, 14, "800 kr")},
+			opts: pkg.RankOptions{Weights: pkg.RankWeights{Preferred: 1}, After: 12 * time.Hour, Before: 17 * time.Hour},
+			want: []string{"afternoon", "evening"},
+		},
+		{
+			name: "weights trade off criteria",
+			os:   []pkg.Occasion{rankOccasion("soon and far", 2, 0, 8, "800 kr"), rankOccasion("later and near", 1, 3, 8, "800 kr")},
+			opts: pkg.RankOptions{Weights: pkg.RankWeights{Soon: 1, Distance: 2}, Home: home, Locations: []pkg.Location{near, far}},
+			want: []string{"later and near", "soon and far"},
+		},
+		{
+			name: "ties broken by start",
+			os:   []pkg.Occasion{rankOccasion("second", 1, 1, 8, "800 kr"), rankOccasion("first", 1, 0, 8, "800 kr")},
+			opts: pkg.RankOptions{},
+			want: []string{"first", "second"},
+		},
+		{
+			name: "none",
+			opts: pkg.RankOptions{Weights: pkg.DefaultRankWeights},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ranked := pkg.Rank(tt.os, tt.opts)
+			if len(ranked) != len(tt.want) {
+				t.Fatalf("got %d occasions, want %d", len(ranked), len(tt.want))
+			}
+			for i, r := range ranked {
+				if r.Name != tt.want[i] {
+					t.Errorf("got %v at %d, want %v", r.Name, i, tt.want[i])
+				}
+				if r.Score < 0 || r.Score > 1 {
+					t.Errorf("%v scored %v, want a score in [0, 1]", r.Name, r.Score)
+				}
+				if i > 0 && r.Score > ranked[i-1].Score {
+					t.Errorf("%v scored higher than %v before it", r.Name, ranked[i-1].Name)
+				}
+			}
+		})
+	}
+}
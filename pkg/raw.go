@@ -0,0 +1,73 @@
+// Copyright © 2017 Sebastian Mandrean <sebastian.mandrean@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package pkg
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"net/http"
+	"strings"
+)
+
+// ErrInvalidJSON is returned by Raw for a body that isn't valid JSON
+var ErrInvalidJSON = errors.New("body is not valid JSON")
+
+// Raw sends the JSON body as is to resource, e.g. "search-information", with the headers set by NewRequest,
+// for calling endpoints the library doesn't wrap yet. An empty body is sent as {}. Unlike the other methods
+// Raw doesn't fail on a non-2xx status. The caller must close the response body.
+func (tc *TrafikverketClient) Raw(ctx context.Context, method string, resource string, body []byte) (*http.Response, error) {
+	resource = "/" + strings.TrimPrefix(strings.TrimPrefix(resource, "/"), "Boka/")
+	method = strings.ToUpper(method)
+	ctx, span := tc.tracer.Start(ctx, method+" "+resource,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("http.request.method", method),
+			attribute.String("trafikverket.endpoint", strings.TrimPrefix(resource, "/")),
+		),
+	)
+	defer span.End()
+
+	if len(body) == 0 {
+		body = []byte("{}")
+	}
+	if !json.Valid(body) {
+		return nil, endSpan(span, ErrInvalidJSON)
+	}
+
+	// create request
+	req, err := newRequest(ctx, tc.baseURL, method, resource, json.RawMessage(body))
+	if err != nil {
+		return nil, endSpan(span, err)
+	}
+	span.SetAttributes(attribute.Int64("http.request.body.size", req.ContentLength))
+
+	// make request
+	res, err := tc.Do(req)
+	if err != nil {
+		return res, endSpan(span, err)
+	}
+	span.SetAttributes(attribute.Int("http.response.status_code", res.StatusCode))
+
+	return res, nil
+}
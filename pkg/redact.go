@@ -0,0 +1,69 @@
+// Copyright © 2017 Sebastian Mandrean <sebastian.mandrean@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package pkg
+
+import (
+	"regexp"
+)
+
+// DisableRedaction turns off masking of personnummer in debug logs, errors and output.
+// Only use this for local debugging.
+var DisableRedaction = false
+
+// personnummerPattern matches 10 and 12 digit personnummer/samordningsnummer with or without separator
+var personnummerPattern = regexp.MustCompile(`\b((?:19|20)?\d{2})(0[1-9]|1[0-2])(0[1-9]|[12]\d|3[01]|6[1-9]|[78]\d|9[01])([-+]?)\d{4}\b`)
+
+// RedactString masks the last four digits of any personnummer in s, unless DisableRedaction is set
+func RedactString(s string) string {
+	if DisableRedaction {
+		return s
+	}
+	return MaskPersonnummer(s)
+}
+
+// MaskPersonnummer masks the last four digits of any personnummer in s, regardless of DisableRedaction.
+// Use it for data that is persisted or shared, where redaction must not be turned off.
+func MaskPersonnummer(s string) string {
+	return personnummerPattern.ReplaceAllString(s, "${1}${2}${3}${4}****")
+}
+
+// RedactError wraps err so its message has any personnummer masked
+func RedactError(err error) error {
+	if err == nil {
+		return nil
+	}
+	if _, ok := err.(*redactedError); ok {
+		return err
+	}
+	return &redactedError{err: err}
+}
+
+type redactedError struct {
+	err error
+}
+
+func (e *redactedError) Error() string {
+	return RedactString(e.err.Error())
+}
+
+func (e *redactedError) Unwrap() error {
+	return e.err
+}
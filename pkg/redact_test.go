@@ -0,0 +1,93 @@
+// Copyright © 2017 Sebastian Mandrean <sebastian.mandrean@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package pkg_test
+
+import (
+	"errors"
+	"github.com/mandrean/go-trafikverket/pkg"
+	"testing"
+)
+
+func TestMaskPersonnummer(t *testing.T) {
+	tests := []struct {
+		name string
+		s    string
+		want string
+	}{
+		{"12 digits", "199001010017", "19900101****"},
+		{"12 digits with separator", "19900101-0017", "19900101-****"},
+		{"10 digits", "9001010017", "900101****"},
+		{"10 digits with separator", "900101-0017", "900101-****"},
+		{"10 digits aged 100 or more", "900101+0017", "900101+****"},
+		{"samordningsnummer", "199001610017", "19900161****"},
+		{"in text", "invalid booking session 199001010017: 400", "invalid booking session 19900101****: 400"},
+		{"in JSON", `{"socialSecurityNumber":"199001010017"}`, `{"socialSecurityNumber":"19900101****"}`},
+		{"several", "199001010017 and 200512312389", "19900101**** and 20051231****"},
+		{"invalid month", "199013010017", "199013010017"},
+		{"invalid day", "199001320017", "199001320017"},
+		{"longer number", "12199001010017", "12199001010017"},
+		{"location ID", "1000140", "1000140"},
+		{"date", "2024-01-01T08:00:00Z", "2024-01-01T08:00:00Z"},
+		{"empty", "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := pkg.MaskPersonnummer(tt.s); got != tt.want {
+				t.Errorf("MaskPersonnummer(%q) = %q, want %q", tt.s, got, tt.want)
+			}
+			if got := pkg.RedactString(tt.s); got != tt.want {
+				t.Errorf("RedactString(%q) = %q, want %q", tt.s, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDisableRedaction(t *testing.T) {
+	pkg.DisableRedaction = true
+	defer func() { pkg.DisableRedaction = false }()
+
+	s := "ssn 199001010017"
+	if got := pkg.RedactString(s); got != s {
+		t.Errorf("RedactString(%q) = %q with redaction disabled, want it unchanged", s, got)
+	}
+	if got, want := pkg.MaskPersonnummer(s), "ssn 19900101****"; got != want {
+		t.Errorf("MaskPersonnummer(%q) = %q with redaction disabled, want %q", s, got, want)
+	}
+}
+
+func TestRedactError(t *testing.T) {
+	cause := errors.New("booking session 199001010017 not found")
+	err := pkg.RedactError(cause)
+
+	if got, want := err.Error(), "booking session 19900101**** not found"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if !errors.Is(err, cause) {
+		t.Error("the redacted error doesn't wrap its cause")
+	}
+	if pkg.RedactError(err) != err {
+		t.Error("redacting a redacted error wrapped it again")
+	}
+	if pkg.RedactError(nil) != nil {
+		t.Error("redacting nil returned an error")
+	}
+}
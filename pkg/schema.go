@@ -0,0 +1,355 @@
+// Copyright © 2017 Sebastian Mandrean <sebastian.mandrean@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package pkg
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DriftKind is how a response differs from the struct it is decoded into
+type DriftKind string
+
+const (
+	// DriftUnknown is a field in the response the struct doesn't have, which decoding drops
+	DriftUnknown DriftKind = "unknown"
+	// DriftMissing is a field of the struct the response doesn't have, which decoding leaves as the zero value
+	DriftMissing DriftKind = "missing"
+	// DriftType is a field whose JSON type doesn't match the struct's, which fails decoding
+	DriftType DriftKind = "type"
+)
+
+type (
+	// Drift is a difference between a response and the struct it is decoded into
+	Drift struct {
+		// Path is the yaml/json path of the field, with [] for array elements, e.g. data.locations[].name
+		Path   string    `yaml:"path"`
+		Kind   DriftKind `yaml:"kind"`
+		Detail string    `yaml:"detail,omitempty"`
+	}
+
+	// SchemaError is returned by a client using WithStrictDecoding when a response has drifted
+	SchemaError struct {
+		Resource string  `yaml:"resource"`
+		Drifts   []Drift `yaml:"drifts"`
+		// Err is the error decoding the response, if the drift made it fail, e.g. a *json.UnmarshalTypeError
+		Err error `yaml:"-"`
+	}
+)
+
+var timeType = reflect.TypeOf(time.Time{})
+
+func (d Drift) String() string {
+	if d.Detail == "" {
+		return fmt.Sprintf("%v %v", d.Kind, d.Path)
+	}
+	return fmt.Sprintf("%v %v (%v)", d.Kind, d.Path, d.Detail)
+}
+
+func (e *SchemaError) Error() string {
+	ds := make([]string, len(e.Drifts))
+	for i, d := range e.Drifts {
+		ds[i] = d.String()
+	}
+	return fmt.Sprintf("%v response has drifted from the library: %v", e.Resource, strings.Join(ds, "; "))
+}
+
+func (e *SchemaError) Unwrap() error {
+	return e.Err
+}
+
+// WithStrictDecoding fails requests whose responses have fields the response structs don't, lack fields they
+// have, or have fields of another type, with a *SchemaError. Without it unknown fields are silently dropped.
+func WithStrictDecoding() ClientOption {
+	return func(tc *TrafikverketClient) {
+		tc.strict = true
+	}
+}
+
+// decodeStrict decodes data into v after comparing them, so that drift which fails decoding, like a changed
+// type, is returned as a *SchemaError wrapping the decoding error rather than as the bare decoding error
+func decodeStrict(resource string, data []byte, v interface{}) error {
+	drifts, cerr := Compare(data, v)
+	err := json.Unmarshal(data, v)
+	if cerr == nil && len(drifts) > 0 {
+		return &SchemaError{Resource: resource, Drifts: drifts, Err: err}
+	}
+	return err
+}
+
+// Compare reports how the JSON in data differs from the struct v, a pointer to which data is decoded into.
+// Fields are matched case-insensitively, like encoding/json does. A field of an array element is reported
+// as missing only if it is missing from every element. Null values and interface{} fields match anything.
+func Compare(data []byte, v interface{}) ([]Drift, error) {
+	d := json.NewDecoder(bytes.NewReader(data))
+	d.UseNumber()
+	var raw interface{}
+	if err := d.Decode(&raw); err != nil {
+		return nil, err
+	}
+
+	drifts := compare("", raw, reflect.TypeOf(v))
+	sort.SliceStable(drifts, func(i, j int) bool {
+		return drifts[i].Path < drifts[j].Path
+	})
+
+	return drifts, nil
+}
+
+// compare compares the decoded JSON value raw at path with type t
+func compare(path string, raw interface{}, t reflect.Type) []Drift {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if raw == nil || t.Kind() == reflect.Interface {
+		return nil
+	}
+
+	mismatch := func(expected string) []Drift {
+		return []Drift{{Path: path, Kind: DriftType, Detail: fmt.Sprintf("expected %v, got %v", expected, jsonType(raw))}}
+	}
+
+	if t == timeType {
+		if _, ok := raw.(string); !ok {
+			return mismatch("string")
+		}
+		return nil
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		obj, ok := raw.(map[string]interface{})
+		if !ok {
+			return mismatch("object")
+		}
+		return compareStruct(path, obj, t)
+
+	case reflect.Map:
+		obj, ok := raw.(map[string]interface{})
+		if !ok {
+			return mismatch("object")
+		}
+		var drifts []Drift
+		for k, v := range obj {
+			drifts = append(drifts, compare(join(path, k), v, t.Elem())...)
+		}
+		return drifts
+
+	case reflect.Slice, reflect.Array:
+		arr, ok := raw.([]interface{})
+		if !ok {
+			return mismatch("array")
+		}
+		return compareElements(path+"[]", arr, t.Elem())
+
+	case reflect.String:
+		if _, ok := raw.(string); !ok {
+			return mismatch("string")
+		}
+
+	case reflect.Bool:
+		if _, ok := raw.(bool); !ok {
+			return mismatch("boolean")
+		}
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		if _, ok := raw.(json.Number); !ok {
+			return mismatch("number")
+		}
+	}
+
+	return nil
+}
+
+// compareStruct compares the fields of obj with those of the struct t
+func compareStruct(path string, obj map[string]interface{}, t reflect.Type) []Drift {
+	type field struct {
+		name string
+		typ  reflect.Type
+	}
+	fields := make(map[string]field)
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" {
+			continue
+		}
+		name := strings.Split(f.Tag.Get("yaml"), ",")[0]
+		if name == "" || name == "-" {
+			name = f.Name
+		}
+		fields[strings.ToLower(f.Name)] = field{name, f.Type}
+	}
+
+	var drifts []Drift
+	seen := make(map[string]bool)
+	for k, v := range obj {
+		f, ok := fields[strings.ToLower(k)]
+		if !ok {
+			drifts = append(drifts, Drift{Path: join(path, k), Kind: DriftUnknown, Detail: jsonType(v)})
+			continue
+		}
+		seen[strings.ToLower(k)] = true
+		drifts = append(drifts, compare(join(path, f.name), v, f.typ)...)
+	}
+	for k, f := range fields {
+		if !seen[k] {
+			drifts = append(drifts, Drift{Path: join(path, f.name), Kind: DriftMissing})
+		}
+	}
+
+	return drifts
+}
+
+// compareElements compares every element of arr with t, reporting each drift once
+func compareElements(path string, arr []interface{}, t reflect.Type) []Drift {
+	var drifts []Drift
+	seen := make(map[Drift]bool)
+	missing := make(map[Drift]int)
+	for _, v := range arr {
+		for _, d := range compare(path, v, t) {
+			if d.Kind == DriftMissing {
+				missing[d]++
+				continue
+			}
+			if !seen[d] {
+				seen[d] = true
+				drifts = append(drifts, d)
+			}
+		}
+	}
+	for d, n := range missing {
+		if n == len(arr) {
+			drifts = append(drifts, d)
+		}
+	}
+
+	return drifts
+}
+
+// join appends the field name to path
+func join(path string, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+// jsonType names the JSON type of a decoded value
+func jsonType(v interface{}) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case json.Number:
+		return "number"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	default:
+		return "object"
+	}
+}
+
+// SchemaReport is the drift of an endpoint's response from the library
+type SchemaReport struct {
+	Resource string  `yaml:"resource"`
+	Status   int     `yaml:"status"`
+	Drifts   []Drift `yaml:"drifts"`
+	Error    string  `yaml:"error,omitempty"`
+}
+
+// CheckSchemas calls licence-information, search-information and occasion-bundles for the booking session
+// and compares each response with the library's structs, to notice when Trafikverket changes its responses.
+// Occasions are queried at locationID, or the first location if 0, with Trafikverket's default query.
+func (tc *TrafikverketClient) CheckSchemas(ctx context.Context, bs BookingSession, locationID int) []SchemaReport {
+	ctx, span := tc.tracer.Start(ctx, "CheckSchemas")
+	defer span.End()
+
+	var li LicenceInformationResponse
+	reports := []SchemaReport{tc.checkSchema(ctx, "licence-information", "{}", &li)}
+	if bs.LicenceID == 0 {
+		bs.LicenceID = li.Data.LicenceID
+	}
+	if bs.LicenceID == 0 {
+		bs.LicenceID = DefaultLicenceID
+	}
+
+	var si SearchInformationResponse
+	reports = append(reports, tc.checkSchema(ctx, "search-information", &SearchInformationRequest{BookingSession: bs}, &si))
+
+	q := OccasionBundleQuery{LocationID: locationID}
+	if q.LocationID == 0 && len(si.Data.Locations) > 0 {
+		q.LocationID = int(si.Data.Locations[0].ID)
+	}
+	q, _ = ApplyDefaults(&si, q)
+	var ob OccasionBundlesResponse
+	reports = append(reports, tc.checkSchema(ctx, "occasion-bundles", &OccasionBundlesRequest{BookingSession: bs, OccasionBundleQuery: q}, &ob))
+
+	return reports
+}
+
+// checkSchema sends the payload to resource, compares the response with v and decodes it into v
+func (tc *TrafikverketClient) checkSchema(ctx context.Context, resource string, payload interface{}, v interface{}) SchemaReport {
+	r := SchemaReport{Resource: resource}
+	fail := func(err error) SchemaReport {
+		r.Error = RedactError(err).Error()
+		return r
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fail(err)
+	}
+	res, err := tc.Raw(ctx, "POST", resource, body)
+	if err != nil {
+		return fail(err)
+	}
+	defer res.Body.Close()
+	r.Status = res.StatusCode
+
+	b, err := io.ReadAll(res.Body)
+	if err != nil {
+		return fail(err)
+	}
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return fail(&StatusError{StatusCode: res.StatusCode, Status: res.Status})
+	}
+	// compare first, so that the drift is reported even if it fails decoding
+	if r.Drifts, err = Compare(b, v); err != nil {
+		return fail(err)
+	}
+	if err := json.Unmarshal(b, v); err != nil {
+		return fail(err)
+	}
+
+	return r
+}
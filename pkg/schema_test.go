@@ -0,0 +1,159 @@
+// Copyright © 2017 Sebastian Mandrean <sebastian.mandrean@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package pkg_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/mandrean/go-trafikverket/pkg"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"testing"
+)
+
+// serveBodies starts a server responding to each resource with its body in bodies
+func serveBodies(t *testing.T, bodies map[string]string) *httptest.Server {
+	t.Helper()
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, ok := bodies[path.Base(r.URL.Path)]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(b))
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// hasDrift reports whether ds has a drift of kind at path
+func hasDrift(ds []pkg.Drift, kind pkg.DriftKind, path string) bool {
+	for _, d := range ds {
+		if d.Kind == kind && d.Path == path {
+			return true
+		}
+	}
+	return false
+}
+
+func TestStrictDecoding(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		drift      pkg.Drift
+		decodeFail bool
+	}{
+		{
+			name:  "unknown field",
+			body:  `{"data":[{"occasions":[{"locationId":1,"newField":true}]}]}`,
+			drift: pkg.Drift{Kind: pkg.DriftUnknown, Path: "data[].occasions[].newField"},
+		},
+		{
+			name:  "missing field",
+			body:  `{"data":[{"occasions":[{"locationId":1}],"cost":"325 kr"}],"status":200,"url":""}`,
+			drift: pkg.Drift{Kind: pkg.DriftMissing, Path: "data[].occasions[].name"},
+		},
+		{
+			name:       "changed type",
+			body:       `{"data":[{"occasions":[{"locationId":"1000140"}]}]}`,
+			drift:      pkg.Drift{Kind: pkg.DriftType, Path: "data[].occasions[].locationId"},
+			decodeFail: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := serveBodies(t, map[string]string{"occasion-bundles": tt.body})
+			tc := pkg.NewClient(pkg.WithBaseURL(s.URL), pkg.WithStrictDecoding())
+
+			_, _, err := tc.OccasionBundles(&pkg.OccasionBundlesRequest{})
+			var se *pkg.SchemaError
+			if !errors.As(err, &se) {
+				t.Fatalf("got error %v, want a *SchemaError", err)
+			}
+			if se.Resource != "occasion-bundles" {
+				t.Errorf("got resource %q, want occasion-bundles", se.Resource)
+			}
+			if !hasDrift(se.Drifts, tt.drift.Kind, tt.drift.Path) {
+				t.Errorf("got drifts %v, want %v", se.Drifts, tt.drift)
+			}
+			var te *json.UnmarshalTypeError
+			if errors.As(err, &te) != tt.decodeFail {
+				t.Errorf("got wrapped decoding error %v, want one: %v", se.Err, tt.decodeFail)
+			}
+		})
+	}
+}
+
+func TestCompareMissing(t *testing.T) {
+	// name is only missing from one of the occasions, and cost and url from every bundle and the response
+	data := `{"data":[{"occasions":[{"locationId":1,"name":"Körprov B"},{"locationId":2}]}],"status":200}`
+	drifts, err := pkg.Compare([]byte(data), &pkg.OccasionBundlesResponse{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, p := range []string{"url", "data[].cost", "data[].occasions[].time"} {
+		if !hasDrift(drifts, pkg.DriftMissing, p) {
+			t.Errorf("got drifts %v, want %v missing", drifts, p)
+		}
+	}
+	for _, p := range []string{"status", "data[].occasions[].locationId", "data[].occasions[].name"} {
+		if hasDrift(drifts, pkg.DriftMissing, p) {
+			t.Errorf("got %v missing, but it's present", p)
+		}
+	}
+}
+
+func TestStrictDecodingInvalidJSON(t *testing.T) {
+	s := serveBodies(t, map[string]string{"occasion-bundles": `{"data":`})
+	tc := pkg.NewClient(pkg.WithBaseURL(s.URL), pkg.WithStrictDecoding())
+
+	_, _, err := tc.OccasionBundles(&pkg.OccasionBundlesRequest{})
+	var se *pkg.SchemaError
+	if err == nil || errors.As(err, &se) {
+		t.Errorf("got error %v, want a decoding error", err)
+	}
+}
+
+func TestCheckSchemasReportsDriftFailingDecoding(t *testing.T) {
+	s := serveBodies(t, map[string]string{
+		"licence-information": `{"data":{"licenceId":5}}`,
+		"search-information":  `{"data":{"locations":[{"id":1000140}]}}`,
+		"occasion-bundles":    `{"data":[{"occasions":[{"locationId":"1000140"}]}]}`,
+	})
+	tc := pkg.NewClient(pkg.WithBaseURL(s.URL))
+
+	reports := tc.CheckSchemas(context.Background(), pkg.BookingSession{SocialSecurityNumber: testSSN}, 0)
+	if len(reports) != 3 {
+		t.Fatalf("got %d reports, want 3", len(reports))
+	}
+	r := reports[2]
+	if r.Resource != "occasion-bundles" || r.Error == "" {
+		t.Errorf("got report %+v, want a decoding error for occasion-bundles", r)
+	}
+	if !hasDrift(r.Drifts, pkg.DriftType, "data[].occasions[].locationId") {
+		t.Errorf("got drifts %v, want the changed type of locationId", r.Drifts)
+	}
+}
@@ -21,9 +21,9 @@
 package pkg
 
 import (
-	"encoding/json"
-	"errors"
-	log "github.com/sirupsen/logrus"
+	"context"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"net/http"
 	"time"
 )
@@ -44,51 +44,63 @@ type (
 				Category    string `yaml:"category"`
 				Icon        string `yaml:"icon"`
 			} `yaml:"licences"`
-			LicenceCategories []LicenceCategory `yaml:"licenceCategories"`
-			LocationID        uint64            `yaml:"locationId"`
-			Locations         []Location        `yaml:"locations"`
-			TimeIntervalID    uint64            `yaml:"timeIntervalId"`
-			TimeIntervals     []struct {
-				ID        uint64    `yaml:"id"`
-				StartDate time.Time `yaml:"startDate"`
-				Name      string    `yaml:"name"`
-			} `yaml:"timeIntervals"`
-			ShowLanguage bool   `yaml:"showLanguage"`
-			LanguageID   uint64 `yaml:"languageId"`
-			Languages    []struct {
-				ID          uint64 `yaml:"id"`
-				Name        string `yaml:"name"`
-				LocationIDs []int  `yaml:"locationIds"`
-			} `yaml:"languages"`
-			ShowVehicleType bool   `yaml:"showVehicleType"`
-			VehicleTypeID   uint64 `yaml:"vehicleTypeId"`
-			VehicleTypes    []struct {
-				ID   int    `yaml:"id"`
-				Name string `yaml:"name"`
-			} `yaml:"vehicleTypes"`
-			ShowTachographType bool   `yaml:"showTachographType"`
-			TachographTypeID   uint64 `yaml:"tachographTypeId"`
-			TachographTypes    []struct {
-				ID   int    `yaml:"id"`
-				Name string `yaml:"name"`
-			} `yaml:"tachographTypes"`
-			ShowOccasionChoices bool   `yaml:"showOccasionChoices"`
-			OccasionChoiceID    uint64 `yaml:"occasionChoiceId"`
-			OccasionChoices     []struct {
-				ID   uint64 `yaml:"id"`
-				Name string `yaml:"name"`
-			} `yaml:"occasionChoices"`
-			ShowExaminationType bool   `yaml:"showExaminationType"`
-			ExaminationTypeID   uint64 `yaml:"examinationTypeId"`
-			ExaminationTypes    []struct {
-				ID   uint64 `yaml:"id"`
-				Name string `yaml:"name"`
-			} `yaml:"examinationTypes"`
+			LicenceCategories   []LicenceCategory `yaml:"licenceCategories"`
+			LocationID          uint64            `yaml:"locationId"`
+			Locations           []Location        `yaml:"locations"`
+			TimeIntervalID      uint64            `yaml:"timeIntervalId"`
+			TimeIntervals       []TimeInterval    `yaml:"timeIntervals"`
+			ShowLanguage        bool              `yaml:"showLanguage"`
+			LanguageID          uint64            `yaml:"languageId"`
+			Languages           []Language        `yaml:"languages"`
+			ShowVehicleType     bool              `yaml:"showVehicleType"`
+			VehicleTypeID       uint64            `yaml:"vehicleTypeId"`
+			VehicleTypes        []VehicleType     `yaml:"vehicleTypes"`
+			ShowTachographType  bool              `yaml:"showTachographType"`
+			TachographTypeID    uint64            `yaml:"tachographTypeId"`
+			TachographTypes     []TachographType  `yaml:"tachographTypes"`
+			ShowOccasionChoices bool              `yaml:"showOccasionChoices"`
+			OccasionChoiceID    uint64            `yaml:"occasionChoiceId"`
+			OccasionChoices     []OccasionChoice  `yaml:"occasionChoices"`
+			ShowExaminationType bool              `yaml:"showExaminationType"`
+			ExaminationTypeID   uint64            `yaml:"examinationTypeId"`
+			ExaminationTypes    []ExaminationType `yaml:"examinationTypes"`
 		} `yaml:"data"`
 		Status int    `yaml:"status"`
 		URL    string `yaml:"url"`
 	}
 
+	TimeInterval struct {
+		ID        uint64    `yaml:"id"`
+		StartDate time.Time `yaml:"startDate"`
+		Name      string    `yaml:"name"`
+	}
+
+	Language struct {
+		ID          uint64 `yaml:"id"`
+		Name        string `yaml:"name"`
+		LocationIDs []int  `yaml:"locationIds"`
+	}
+
+	VehicleType struct {
+		ID   int    `yaml:"id"`
+		Name string `yaml:"name"`
+	}
+
+	TachographType struct {
+		ID   int    `yaml:"id"`
+		Name string `yaml:"name"`
+	}
+
+	OccasionChoice struct {
+		ID   uint64 `yaml:"id"`
+		Name string `yaml:"name"`
+	}
+
+	ExaminationType struct {
+		ID   uint64 `yaml:"id"`
+		Name string `yaml:"name"`
+	}
+
 	Location struct {
 		ID      uint64 `yaml:"id"`
 		Name    string `yaml:"name"`
@@ -99,52 +111,56 @@ type (
 			City           string `yaml:"city"`
 			CareOf         string `yaml:"careOf"`
 		} `yaml:"address"`
-		Coordinates struct {
-			Latitude  float64 `yaml:"latitude"`
-			Longitude float64 `yaml:"longitude"`
-		} `yaml:"coordinates"`
+		Coordinates Coordinates `yaml:"coordinates"`
+	}
+
+	Coordinates struct {
+		Latitude  float64 `yaml:"latitude"`
+		Longitude float64 `yaml:"longitude"`
 	}
 )
 
 // SearchInformation searches and returns different types of available information
 // associated with the provided social security number, like available licence categories, exam locations etc.
 func (tc *TrafikverketClient) SearchInformation(body *SearchInformationRequest) (*SearchInformationResponse, *http.Response, error) {
-	// create request
-	req, err := NewRequest("POST", "/search-information", &body)
-	if err != nil {
-		return nil, nil, err
-	}
+	return tc.SearchInformationContext(context.Background(), body)
+}
 
-	// make request
-	res, err := tc.Do(req)
+// SearchInformationContext is like SearchInformation but with a context
+func (tc *TrafikverketClient) SearchInformationContext(ctx context.Context, body *SearchInformationRequest) (*SearchInformationResponse, *http.Response, error) {
+	bs, err := tc.bookingSession(body.BookingSession)
 	if err != nil {
 		return nil, nil, err
 	}
-	defer res.Body.Close()
+	b := *body
+	b.BookingSession = bs
 
-	if res.StatusCode < 200 || res.StatusCode > 299 {
-		return nil, res, errors.New(res.Status)
-	}
-
-	// decode response
 	var resp SearchInformationResponse
-	err = json.NewDecoder(res.Body).Decode(&resp)
+	res, err := tc.do(ctx, "/search-information", &b, &resp)
 	if err != nil {
 		return nil, res, err
 	}
 
-	b, _ := json.Marshal(resp)
-	log.Debugln(string(b))
-
 	return &resp, res, nil
 }
 
 // Locations returns the available examination locations for the provided social security number
 func (tc *TrafikverketClient) Locations(body *SearchInformationRequest) (*[]Location, *http.Response, error) {
-	resp, res, err := tc.SearchInformation(body)
+	return tc.LocationsContext(context.Background(), body)
+}
+
+// LocationsContext is like Locations but with a context
+func (tc *TrafikverketClient) LocationsContext(ctx context.Context, body *SearchInformationRequest) (*[]Location, *http.Response, error) {
+	ctx, span := tc.tracer.Start(ctx, "Locations", trace.WithAttributes(
+		attribute.Int("trafikverket.licence_id", body.BookingSession.LicenceID),
+	))
+	defer span.End()
+
+	resp, res, err := tc.SearchInformationContext(ctx, body)
 	if err != nil {
-		return nil, res, err
+		return nil, res, endSpan(span, err)
 	}
+	span.SetAttributes(attribute.Int("trafikverket.results", len(resp.Data.Locations)))
 
 	return &resp.Data.Locations, res, nil
 }
@@ -0,0 +1,154 @@
+// Copyright © 2017 Sebastian Mandrean <sebastian.mandrean@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package server
+
+import (
+	"fmt"
+	"github.com/mandrean/go-trafikverket/pkg"
+	"reflect"
+	"strings"
+	"time"
+)
+
+type (
+	parameter struct {
+		name        string
+		typ         string
+		required    bool
+		description string
+	}
+)
+
+var (
+	bookingSessionParameters = []parameter{
+		{"ssn", "string", true, "Social security number (personnummer)"},
+		{"licence", "integer", false, fmt.Sprintf("Licence ID/type. Default: %d", pkg.DefaultLicenceID)},
+		{"bookingMode", "integer", false, "Booking mode ID/type. Default: 0"},
+		{"ignoreDebt", "boolean", false, "Ignore debt. Default: false"},
+		{"examinationType", "integer", false, "Examination type ID. Default: 0"},
+	}
+
+	occasionParameters = []parameter{
+		{"location", "integer", true, "Location ID"},
+		{"startDate", "string", false, "Start date (RFC3339)"},
+		{"language", "integer", false, "Language ID. Default: the licence's default. Must be applicable to the licence"},
+		{"vehicleType", "integer", false, "Vehicle type ID. Default: the licence's default. Must be applicable to the licence"},
+		{"tachographType", "integer", false, "Tachograph type ID. Default: the licence's default. Must be applicable to the licence"},
+		{"occasionChoice", "integer", false, "Occasion choice ID. Default: the licence's default. Must be applicable to the licence"},
+	}
+
+	timeType = reflect.TypeOf(time.Time{})
+)
+
+// OpenAPI returns an OpenAPI 3 document describing the server's API.
+// Response schemas are generated from the pkg types, so they never drift from what's actually served.
+func OpenAPI() map[string]interface{} {
+	return map[string]interface{}{
+		"openapi": "3.0.3",
+		"info": map[string]interface{}{
+			"title":   "go-trafikverket",
+			"version": "1.0.0",
+		},
+		"paths": map[string]interface{}{
+			"/licence-categories": operation("List licence categories", nil, []pkg.LicenceCategory{}),
+			"/locations":          operation("List exam locations", bookingSessionParameters, []pkg.Location{}),
+			"/occasions":          operation("List exam occasions", append(append([]parameter{}, bookingSessionParameters...), occasionParameters...), []pkg.Occasion{}),
+		},
+	}
+}
+
+func operation(summary string, params []parameter, resp interface{}) map[string]interface{} {
+	ps := make([]interface{}, 0, len(params))
+	for _, p := range params {
+		ps = append(ps, map[string]interface{}{
+			"name":        p.name,
+			"in":          "query",
+			"required":    p.required,
+			"description": p.description,
+			"schema":      map[string]interface{}{"type": p.typ},
+		})
+	}
+
+	return map[string]interface{}{
+		"get": map[string]interface{}{
+			"summary":    summary,
+			"parameters": ps,
+			"responses": map[string]interface{}{
+				"200": content("OK", schema(reflect.TypeOf(resp))),
+				"400": content("Invalid request", schema(reflect.TypeOf(errorResponse{}))),
+				"405": content("Method not allowed", schema(reflect.TypeOf(errorResponse{}))),
+				"502": content("Upstream error", schema(reflect.TypeOf(errorResponse{}))),
+				"503": content("Rate limited, the request was cancelled while waiting for its turn", schema(reflect.TypeOf(errorResponse{}))),
+			},
+		},
+	}
+}
+
+func content(description string, s map[string]interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		"description": description,
+		"content": map[string]interface{}{
+			"application/json": map[string]interface{}{"schema": s},
+		},
+	}
+}
+
+// schema generates a JSON schema for t matching how encoding/json marshals it
+func schema(t reflect.Type) map[string]interface{} {
+	if t == timeType {
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr:
+		return schema(t.Elem())
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		return map[string]interface{}{"type": "array", "items": schema(t.Elem())}
+	case reflect.Struct:
+		props := make(map[string]interface{})
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if f.PkgPath != "" {
+				continue
+			}
+			name := f.Name
+			if tag := strings.Split(f.Tag.Get("json"), ",")[0]; tag == "-" {
+				continue
+			} else if tag != "" {
+				name = tag
+			}
+			props[name] = schema(f.Type)
+		}
+		return map[string]interface{}{"type": "object", "properties": props}
+	default:
+		// interface{} etc. can be anything
+		return map[string]interface{}{}
+	}
+}
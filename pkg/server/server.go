@@ -0,0 +1,335 @@
+// Copyright © 2017 Sebastian Mandrean <sebastian.mandrean@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/mandrean/go-trafikverket/pkg"
+	"github.com/mandrean/go-trafikverket/pkg/personnummer"
+	log "github.com/sirupsen/logrus"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type (
+	// Server exposes the Förarprov API as a local REST/JSON API
+	Server struct {
+		client  pkg.Client
+		cache   *cache
+		limiter *limiter
+		mux     *http.ServeMux
+		hook    func(query pkg.OccasionBundleQuery, os []pkg.Occasion)
+	}
+
+	// Options configures a Server
+	Options struct {
+		// CacheTTL is how long successful responses are cached. Zero disables caching.
+		CacheTTL time.Duration
+		// RateInterval is the minimum time between two requests to Trafikverket. Zero disables rate limiting.
+		RateInterval time.Duration
+		// OccasionsHook, if set, is called with every successfully fetched list of occasions
+		OccasionsHook func(query pkg.OccasionBundleQuery, os []pkg.Occasion)
+	}
+
+	errorResponse struct {
+		Error string `yaml:"error" json:"error"`
+	}
+)
+
+// New creates a new Server using the provided client for upstream requests
+func New(tc pkg.Client, opts Options) *Server {
+	s := &Server{
+		client:  tc,
+		cache:   newCache(opts.CacheTTL),
+		limiter: newLimiter(opts.RateInterval),
+		mux:     http.NewServeMux(),
+		hook:    opts.OccasionsHook,
+	}
+
+	s.mux.HandleFunc("/licence-categories", s.get(s.licenceCategories))
+	s.mux.HandleFunc("/locations", s.get(s.locations))
+	s.mux.HandleFunc("/occasions", s.get(s.occasions))
+	s.mux.HandleFunc("/openapi.json", s.openAPI)
+
+	return s
+}
+
+// Handle registers an additional handler, e.g. for metrics or health checks
+func (s *Server) Handle(pattern string, handler http.Handler) {
+	s.mux.Handle(pattern, handler)
+}
+
+// ServeHTTP implements http.Handler
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	log.Debugf("%v %v", r.Method, r.URL.Path)
+	s.mux.ServeHTTP(w, r)
+}
+
+// get wraps an endpoint handler with method checking, caching and rate limiting
+func (s *Server) get(h func(r *http.Request) (interface{}, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %v not allowed", r.Method))
+			return
+		}
+
+		// serve from cache
+		key := r.URL.Path + "?" + r.URL.Query().Encode()
+		if b, ok := s.cache.get(key); ok {
+			writeBody(w, http.StatusOK, b)
+			return
+		}
+
+		// wait for our turn before calling upstream
+		if err := s.limiter.wait(r.Context()); err != nil {
+			writeError(w, http.StatusServiceUnavailable, err)
+			return
+		}
+
+		v, err := h(r)
+		if err != nil {
+			var ve *validationError
+			var qe *pkg.ValidationError
+			if errors.As(err, &ve) || errors.As(err, &qe) {
+				writeError(w, http.StatusBadRequest, err)
+				return
+			}
+			log.Errorln(err)
+			writeError(w, http.StatusBadGateway, err)
+			return
+		}
+
+		b, err := json.Marshal(v)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+		s.cache.set(key, b)
+		writeBody(w, http.StatusOK, b)
+	}
+}
+
+func (s *Server) licenceCategories(r *http.Request) (interface{}, error) {
+	lcs, _, err := s.client.LicenceCategoriesContext(r.Context())
+	return lcs, err
+}
+
+func (s *Server) locations(r *http.Request) (interface{}, error) {
+	p := params{r: r}
+	body := &pkg.SearchInformationRequest{
+		BookingSession: p.bookingSession(),
+	}
+	if err := p.err(); err != nil {
+		return nil, err
+	}
+
+	ls, _, err := s.client.LocationsContext(r.Context(), body)
+	return ls, err
+}
+
+func (s *Server) occasions(r *http.Request) (interface{}, error) {
+	p := params{r: r}
+	body := &pkg.OccasionBundlesRequest{
+		BookingSession: p.bookingSession(),
+		OccasionBundleQuery: pkg.OccasionBundleQuery{
+			StartDate:         p.time("startDate"),
+			LocationID:        p.requiredInt("location"),
+			LanguageID:        p.int("language", 0),
+			VehicleTypeID:     p.int("vehicleType", 0),
+			TachographTypeID:  p.int("tachographType", 0),
+			OccasionChoiceID:  p.int("occasionChoice", 0),
+			ExaminationTypeID: p.int("examinationType", 0),
+		},
+	}
+	if err := p.err(); err != nil {
+		return nil, err
+	}
+
+	// fill in Trafikverket's defaults for the licence, rejecting parameters it doesn't apply to
+	info, err := s.searchInformation(r.Context(), body.BookingSession)
+	if err != nil {
+		return nil, err
+	}
+	q, ignored := pkg.ApplyDefaults(info, body.OccasionBundleQuery)
+	if len(ignored) > 0 {
+		ve := &validationError{}
+		for _, f := range ignored {
+			ve.errs = append(ve.errs, fmt.Sprintf("%v is not applicable to licence %d", strings.TrimSuffix(f, "Id"), body.BookingSession.LicenceID))
+		}
+		return nil, ve
+	}
+	if err := pkg.CheckQuery(info, q); err != nil {
+		return nil, err
+	}
+	body.OccasionBundleQuery = q
+
+	os, _, err := s.client.OccasionsContext(r.Context(), body)
+	if err == nil && s.hook != nil {
+		s.hook(body.OccasionBundleQuery, *os)
+	}
+	return os, err
+}
+
+// searchInformation returns the search information for bs, cached per booking session. On a cache miss it
+// uses the upstream request the endpoint already waited for, and waits for another one before returning.
+func (s *Server) searchInformation(ctx context.Context, bs pkg.BookingSession) (*pkg.SearchInformationResponse, error) {
+	key := fmt.Sprintf("search-information?%+v", bs)
+	if b, ok := s.cache.get(key); ok {
+		info := &pkg.SearchInformationResponse{}
+		if err := json.Unmarshal(b, info); err == nil {
+			return info, nil
+		}
+	}
+
+	info, _, err := s.client.SearchInformationContext(ctx, &pkg.SearchInformationRequest{BookingSession: bs})
+	if err != nil {
+		return nil, err
+	}
+	if b, err := json.Marshal(info); err == nil {
+		s.cache.set(key, b)
+	}
+
+	if err := s.limiter.wait(ctx); err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+func (s *Server) openAPI(w http.ResponseWriter, r *http.Request) {
+	b, err := json.Marshal(OpenAPI())
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeBody(w, http.StatusOK, b)
+}
+
+func writeBody(w http.ResponseWriter, status int, b []byte) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(b)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	b, _ := json.Marshal(errorResponse{Error: pkg.RedactString(err.Error())})
+	writeBody(w, status, b)
+}
+
+// validationError is returned for invalid query parameters
+type validationError struct {
+	errs []string
+}
+
+func (e *validationError) Error() string {
+	return "invalid request: " + strings.Join(e.errs, ", ")
+}
+
+// params parses and validates query parameters, collecting all errors
+type params struct {
+	r    *http.Request
+	errs []string
+}
+
+func (p *params) bookingSession() pkg.BookingSession {
+	return pkg.BookingSession{
+		SocialSecurityNumber: p.personnummer("ssn"),
+		LicenceID:            p.int("licence", pkg.DefaultLicenceID),
+		BookingModeID:        p.int("bookingMode", 0),
+		IgnoreDebt:           p.bool("ignoreDebt"),
+		ExaminationTypeID:    p.int("examinationType", 0),
+	}
+}
+
+func (p *params) requiredString(name string) string {
+	v := p.r.URL.Query().Get(name)
+	if v == "" {
+		p.errs = append(p.errs, fmt.Sprintf("%v is required", name))
+	}
+	return v
+}
+
+func (p *params) personnummer(name string) string {
+	v := p.requiredString(name)
+	if v == "" {
+		return ""
+	}
+	ssn, err := personnummer.Normalise(v)
+	if err != nil {
+		p.errs = append(p.errs, fmt.Sprintf("%v: %v", name, err))
+	}
+	return ssn
+}
+
+func (p *params) requiredInt(name string) int {
+	if p.r.URL.Query().Get(name) == "" {
+		p.errs = append(p.errs, fmt.Sprintf("%v is required", name))
+		return 0
+	}
+	return p.int(name, 0)
+}
+
+func (p *params) int(name string, def int) int {
+	v := p.r.URL.Query().Get(name)
+	if v == "" {
+		return def
+	}
+	i, err := strconv.Atoi(v)
+	if err != nil || i < 0 {
+		p.errs = append(p.errs, fmt.Sprintf("%v must be a non-negative integer", name))
+	}
+	return i
+}
+
+func (p *params) bool(name string) bool {
+	v := p.r.URL.Query().Get(name)
+	if v == "" {
+		return false
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		p.errs = append(p.errs, fmt.Sprintf("%v must be a boolean", name))
+	}
+	return b
+}
+
+func (p *params) time(name string) time.Time {
+	v := p.r.URL.Query().Get(name)
+	if v == "" {
+		return time.Time{}
+	}
+	t, err := time.Parse(time.RFC3339, v)
+	if err != nil {
+		p.errs = append(p.errs, fmt.Sprintf("%v must be an RFC3339 timestamp", name))
+	}
+	return t
+}
+
+func (p *params) err() error {
+	if len(p.errs) > 0 {
+		return &validationError{errs: p.errs}
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+// Copyright © 2017 Sebastian Mandrean <sebastian.mandrean@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package server_test
+
+import (
+	"github.com/mandrean/go-trafikverket/pkg/server"
+	"github.com/mandrean/go-trafikverket/pkg/trafikverkettest"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestOccasions(t *testing.T) {
+	tests := []struct {
+		name   string
+		query  string
+		status int
+		body   string
+	}{
+		{"defaults", "location=1000140", http.StatusOK, `"LocationID":1000140`},
+		{"applicable parameter", "location=1000140&vehicleType=4", http.StatusOK, `"LocationID":1000140`},
+		{"inapplicable parameter", "location=1000140&tachographType=9", http.StatusBadRequest, "tachographType is not applicable to licence 5"},
+		{"unknown language", "location=1000140&language=99", http.StatusBadRequest, "language 99 does not exist"},
+		{"language not offered", "location=1000071&language=4", http.StatusBadRequest, "Engelska is not offered at Göteborg"},
+		{"unknown location", "location=99", http.StatusBadRequest, "location 99 is not offered"},
+		{"missing location", "", http.StatusBadRequest, "location"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := trafikverkettest.NewServer(trafikverkettest.DefaultFixtures(time.Now()))
+			defer s.Close()
+			srv := server.New(s.Client(), server.Options{})
+
+			rec := httptest.NewRecorder()
+			srv.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/occasions?ssn=199001010017&"+tt.query, nil))
+			if rec.Code != tt.status {
+				t.Errorf("got status %d, want %d: %v", rec.Code, tt.status, rec.Body)
+			}
+			if !strings.Contains(rec.Body.String(), tt.body) {
+				t.Errorf("got body %v, want it to contain %q", rec.Body, tt.body)
+			}
+		})
+	}
+}
+
+func TestOccasionsCachesDefaults(t *testing.T) {
+	s := trafikverkettest.NewServer(trafikverkettest.DefaultFixtures(time.Now()))
+	defer s.Close()
+	srv := server.New(s.Client(), server.Options{CacheTTL: time.Minute, RateInterval: time.Millisecond})
+
+	// different queries for the same booking session share its defaults
+	for _, q := range []string{"location=1000140", "location=1000072", "location=1000140&vehicleType=4"} {
+		rec := httptest.NewRecorder()
+		srv.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/occasions?ssn=199001010017&"+q, nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%v: got status %d: %v", q, rec.Code, rec.Body)
+		}
+	}
+	if n := s.Requests("search-information"); n != 1 {
+		t.Errorf("got %d search-information requests, want 1", n)
+	}
+	if n := s.Requests("occasion-bundles"); n != 3 {
+		t.Errorf("got %d occasion-bundles requests, want 3", n)
+	}
+}
+
+func TestMethodNotAllowed(t *testing.T) {
+	s := trafikverkettest.NewServer(trafikverkettest.DefaultFixtures(time.Now()))
+	defer s.Close()
+	srv := server.New(s.Client(), server.Options{})
+
+	rec := httptest.NewRecorder()
+	srv.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/locations?ssn=199001010017", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("got status %d, want %d", rec.Code, http.StatusMethodNotAllowed)
+	}
+	if want := `{"error":"method POST not allowed"}`; rec.Body.String() != want {
+		t.Errorf("got body %v, want %v", rec.Body, want)
+	}
+}
+
+func TestOpenAPIErrorResponses(t *testing.T) {
+	for path, op := range server.OpenAPI()["paths"].(map[string]interface{}) {
+		responses := op.(map[string]interface{})["get"].(map[string]interface{})["responses"].(map[string]interface{})
+		for _, status := range []string{"200", "400", "405", "502", "503"} {
+			if _, ok := responses[status]; !ok {
+				t.Errorf("%v: missing %v response", path, status)
+			}
+		}
+	}
+}
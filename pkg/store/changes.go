@@ -0,0 +1,164 @@
+// Copyright © 2017 Sebastian Mandrean <sebastian.mandrean@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package store
+
+import (
+	"fmt"
+	"github.com/mandrean/go-trafikverket/pkg"
+	"sort"
+	"time"
+)
+
+const (
+	Appeared    ChangeKind = "appeared"
+	Disappeared ChangeKind = "disappeared"
+)
+
+type (
+	// ChangeKind is whether an occasion appeared or disappeared
+	ChangeKind string
+
+	// Change is an occasion appearing or disappearing between two consecutive snapshots
+	Change struct {
+		// Time is when the change was first observed
+		Time     time.Time    `yaml:"time"`
+		Kind     ChangeKind   `yaml:"kind"`
+		Occasion pkg.Occasion `yaml:"occasion"`
+	}
+)
+
+// Changes returns the occasions that appeared and disappeared between consecutive snapshots, oldest first.
+// Snapshots are only compared with earlier snapshots of the same query, and only within the date range
+// both of them cover, so occasions moving in or out of the queried window aren't reported as changes.
+// The pages of a sweep, as fetched by pkg.OccasionsBetween, are merged and compared as one snapshot.
+func Changes(snaps []Snapshot) []Change {
+	var changes []Change
+	prev := make(map[string]Snapshot)
+	for _, cur := range sweeps(snaps) {
+		key := seriesKey(cur)
+		p, ok := prev[key]
+		prev[key] = cur
+		if !ok {
+			continue
+		}
+
+		before, after := occasions(p), occasions(cur)
+		from, to := window(p)
+		f, t := window(cur)
+		if f.After(from) {
+			from = f
+		}
+		if to.IsZero() || (!t.IsZero() && t.Before(to)) {
+			to = t
+		}
+		within := func(o pkg.Occasion) bool {
+			s := o.Duration.Start
+			return !s.Before(from) && (to.IsZero() || !s.After(to))
+		}
+
+		for k, o := range after {
+			if _, ok := before[k]; !ok && within(o) {
+				changes = append(changes, Change{Time: cur.Time, Kind: Appeared, Occasion: o})
+			}
+		}
+		for k, o := range before {
+			if _, ok := after[k]; !ok && within(o) {
+				changes = append(changes, Change{Time: cur.Time, Kind: Disappeared, Occasion: o})
+			}
+		}
+	}
+
+	sort.SliceStable(changes, func(i, j int) bool {
+		if !changes[i].Time.Equal(changes[j].Time) {
+			return changes[i].Time.Before(changes[j].Time)
+		}
+		return changes[i].Occasion.Duration.Start.Before(changes[j].Occasion.Duration.Start)
+	})
+
+	return changes
+}
+
+// sweeps merges each snapshot with a later start date than the previous snapshot of the same query into
+// it, as that's the next page of a sweep rather than another poll. A merged snapshot keeps the time and
+// query of its first page.
+func sweeps(snaps []Snapshot) []Snapshot {
+	var merged []Snapshot
+	last := make(map[string]int)
+	pageStart := make(map[string]time.Time)
+	for _, snap := range snaps {
+		key := seriesKey(snap)
+		i, ok := last[key]
+		if ok && snap.Query.StartDate.After(pageStart[key]) {
+			m := &merged[i]
+			m.Response.Data = append(append([]pkg.Bundle(nil), m.Response.Data...), snap.Response.Data...)
+		} else {
+			last[key] = len(merged)
+			merged = append(merged, snap)
+		}
+		pageStart[key] = snap.Query.StartDate
+	}
+	return merged
+}
+
+// ReleaseHours counts appeared occasions by the hour of day (in loc) they were first observed
+func ReleaseHours(changes []Change, loc *time.Location) [24]int {
+	var hours [24]int
+	for _, c := range changes {
+		if c.Kind == Appeared {
+			hours[c.Time.In(loc).Hour()]++
+		}
+	}
+	return hours
+}
+
+// occasions returns the occasions of a snapshot by key
+func occasions(snap Snapshot) map[string]pkg.Occasion {
+	os := make(map[string]pkg.Occasion)
+	for _, d := range snap.Response.Data {
+		for _, o := range d.Occasions {
+			os[o.Key()] = o
+		}
+	}
+	return os
+}
+
+// window returns the date range a snapshot covers: from the later of when it was taken and the
+// queried start date, to its last occasion. A zero end means the snapshot had no occasions, and
+// so covers everything after its start.
+func window(snap Snapshot) (from, to time.Time) {
+	from = snap.Time
+	if snap.Query.StartDate.After(from) {
+		from = snap.Query.StartDate
+	}
+	for _, o := range occasions(snap) {
+		if o.Duration.Start.After(to) {
+			to = o.Duration.Start
+		}
+	}
+	return from, to
+}
+
+// seriesKey identifies snapshots of the same query, ignoring the start date
+func seriesKey(snap Snapshot) string {
+	q := snap.Query
+	return fmt.Sprintf("%d/%d/%d/%d/%d/%d/%d", snap.LicenceID, q.LocationID, q.ExaminationTypeID,
+		q.LanguageID, q.VehicleTypeID, q.TachographTypeID, q.OccasionChoiceID)
+}
@@ -0,0 +1,154 @@
+// Copyright © 2017 Sebastian Mandrean <sebastian.mandrean@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package store_test
+
+import (
+	"github.com/mandrean/go-trafikverket/pkg"
+	"github.com/mandrean/go-trafikverket/pkg/store"
+	"testing"
+	"time"
+)
+
+var day0 = time.Date(2030, 6, 3, 9, 0, 0, 0, time.UTC)
+
+// day returns the time n days after day0, on the hour
+func day(n int, hour int) time.Time {
+	return day0.AddDate(0, 0, n).Add(time.Duration(hour-9) * time.Hour)
+}
+
+// snapshot returns a snapshot of a poll of location at t for start, with occasions starting at starts
+func snapshot(t time.Time, location int, start time.Time, starts ...time.Time) store.Snapshot {
+	snap := store.Snapshot{
+		Time:      t,
+		LicenceID: pkg.DefaultLicenceID,
+		Query:     pkg.OccasionBundleQuery{LocationID: location, StartDate: start},
+	}
+	for _, s := range starts {
+		o := pkg.Occasion{LocationID: location, ExaminationTypeID: 12}
+		o.Duration.Start = s
+		snap.Response.Data = append(snap.Response.Data, pkg.Bundle{Occasions: []pkg.Occasion{o}})
+	}
+	return snap
+}
+
+type change struct {
+	time  time.Time
+	kind  store.ChangeKind
+	start time.Time
+}
+
+func TestChanges(t *testing.T) {
+	var zero time.Time
+
+	tests := []struct {
+		name  string
+		snaps []store.Snapshot
+		want  []change
+	}{
+		{"no snapshots", nil, nil},
+		{"single snapshot", []store.Snapshot{
+			snapshot(day0, 1000140, zero, day(1, 8), day(2, 8)),
+		}, nil},
+		{"unchanged", []store.Snapshot{
+			snapshot(day0, 1000140, zero, day(1, 8), day(2, 8)),
+			snapshot(day0.Add(time.Hour), 1000140, zero, day(1, 8), day(2, 8)),
+		}, nil},
+		{"appeared and disappeared", []store.Snapshot{
+			snapshot(day0, 1000140, zero, day(1, 8), day(2, 8), day(3, 8)),
+			snapshot(day0.Add(time.Hour), 1000140, zero, day(1, 8), day(2, 13), day(3, 8)),
+		}, []change{
+			{day0.Add(time.Hour), store.Disappeared, day(2, 8)},
+			{day0.Add(time.Hour), store.Appeared, day(2, 13)},
+		}},
+		{"consecutive polls", []store.Snapshot{
+			snapshot(day0, 1000140, zero, day(1, 8), day(3, 8)),
+			snapshot(day0.Add(time.Hour), 1000140, zero, day(1, 8), day(2, 8), day(3, 8)),
+			snapshot(day0.Add(2*time.Hour), 1000140, zero, day(1, 8), day(3, 8)),
+		}, []change{
+			{day0.Add(time.Hour), store.Appeared, day(2, 8)},
+			{day0.Add(2 * time.Hour), store.Disappeared, day(2, 8)},
+		}},
+		{"outside the window both cover", []store.Snapshot{
+			// the window moved on: the first occasion passed and a new day was released at the end
+			snapshot(day0, 1000140, zero, day(0, 13), day(1, 8), day(2, 8)),
+			snapshot(day(0, 14), 1000140, zero, day(1, 8), day(2, 8), day(3, 8)),
+		}, nil},
+		{"different locations", []store.Snapshot{
+			snapshot(day0, 1000140, zero, day(1, 8)),
+			snapshot(day0.Add(time.Hour), 1000071, zero, day(2, 8)),
+		}, nil},
+		{"fixed start date", []store.Snapshot{
+			snapshot(day0, 1000140, day(1, 0), day(1, 8), day(2, 8), day(3, 8)),
+			snapshot(day0.Add(time.Hour), 1000140, day(1, 0), day(1, 8), day(3, 8)),
+		}, []change{
+			{day0.Add(time.Hour), store.Disappeared, day(2, 8)},
+		}},
+		{"pages of sweeps", []store.Snapshot{
+			snapshot(day0, 1000140, day(0, 0), day(1, 8), day(2, 8)),
+			snapshot(day0.Add(time.Second), 1000140, day(2, 8).Add(time.Minute), day(3, 8), day(4, 8)),
+			snapshot(day0.Add(time.Hour), 1000140, day(0, 0), day(1, 8), day(2, 8)),
+			snapshot(day0.Add(time.Hour+time.Second), 1000140, day(2, 8).Add(time.Minute), day(3, 13), day(4, 8)),
+		}, []change{
+			{day0.Add(time.Hour), store.Disappeared, day(3, 8)},
+			{day0.Add(time.Hour), store.Appeared, day(3, 13)},
+		}},
+		{"pages of sweeps shifted by a change", []store.Snapshot{
+			snapshot(day0, 1000140, day(0, 0), day(1, 8), day(2, 8)),
+			snapshot(day0.Add(time.Second), 1000140, day(2, 8).Add(time.Minute), day(3, 8)),
+			snapshot(day0.Add(time.Hour), 1000140, day(0, 0), day(1, 8), day(1, 13)),
+			snapshot(day0.Add(time.Hour+time.Second), 1000140, day(1, 13).Add(time.Minute), day(2, 8), day(3, 8)),
+		}, []change{
+			{day0.Add(time.Hour), store.Appeared, day(1, 13)},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := store.Changes(tt.snaps)
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d changes, want %d: %+v", len(got), len(tt.want), got)
+			}
+			for i, w := range tt.want {
+				g := got[i]
+				if !g.Time.Equal(w.time) || g.Kind != w.kind || !g.Occasion.Duration.Start.Equal(w.start) {
+					t.Errorf("change %d: got %v %v at %v, want %v %v at %v", i, g.Kind, g.Occasion.Duration.Start, g.Time, w.kind, w.start, w.time)
+				}
+			}
+		})
+	}
+}
+
+func TestReleaseHours(t *testing.T) {
+	changes := []store.Change{
+		{Time: day(0, 6), Kind: store.Appeared},
+		{Time: day(1, 6), Kind: store.Appeared},
+		{Time: day(1, 6), Kind: store.Disappeared},
+		{Time: day(2, 14), Kind: store.Appeared},
+	}
+
+	hours := store.ReleaseHours(changes, time.UTC)
+	for h, n := range hours {
+		want := map[int]int{6: 2, 14: 1}[h]
+		if n != want {
+			t.Errorf("hour %d: got %d, want %d", h, n, want)
+		}
+	}
+}
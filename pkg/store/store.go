@@ -0,0 +1,166 @@
+// Copyright © 2017 Sebastian Mandrean <sebastian.mandrean@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package store persists occasion bundles polls for analysing availability over time.
+package store
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"github.com/mandrean/go-trafikverket/pkg"
+	bolt "go.etcd.io/bbolt"
+	"sort"
+	"strconv"
+	"time"
+)
+
+var snapshotsBucket = []byte("snapshots")
+
+type (
+	// Store persists snapshots of occasion availability in an embedded bbolt database
+	Store struct {
+		db *bolt.DB
+	}
+
+	// Snapshot is the result of a single occasion bundles poll. The booking session
+	// is not stored, except for the licence, so no social security numbers are persisted.
+	Snapshot struct {
+		Time      time.Time                   `yaml:"time"`
+		LicenceID int                         `yaml:"licenceId"`
+		Query     pkg.OccasionBundleQuery     `yaml:"query"`
+		Response  pkg.OccasionBundlesResponse `yaml:"response"`
+	}
+)
+
+// Open opens or creates the store at path
+func Open(path string) (*Store, error) {
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(snapshotsBucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &Store{db: db}, nil
+}
+
+// Close closes the store
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// Record stores a snapshot of a poll, implementing pkg.Recorder
+func (s *Store) Record(t time.Time, body *pkg.OccasionBundlesRequest, resp *pkg.OccasionBundlesResponse) error {
+	return s.Put(Snapshot{
+		Time:      t,
+		LicenceID: body.BookingSession.LicenceID,
+		Query:     body.OccasionBundleQuery,
+		Response:  *resp,
+	})
+}
+
+// Put stores a snapshot
+func (s *Store) Put(snap Snapshot) error {
+	v, err := json.Marshal(snap)
+	if err != nil {
+		return err
+	}
+
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.Bucket(snapshotsBucket).CreateBucketIfNotExists(locationKey(snap.Query.LocationID))
+		if err != nil {
+			return err
+		}
+
+		// snapshots are keyed by time and a sequence number, so they're iterated in order
+		seq, err := b.NextSequence()
+		if err != nil {
+			return err
+		}
+		return b.Put(snapshotKey(snap.Time, seq), v)
+	})
+}
+
+// LocationIDs returns the locations there are snapshots for
+func (s *Store) LocationIDs() ([]int, error) {
+	var ids []int
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(snapshotsBucket).ForEach(func(k, v []byte) error {
+			id, err := strconv.Atoi(string(k))
+			if err != nil {
+				return err
+			}
+			ids = append(ids, id)
+			return nil
+		})
+	})
+	sort.Ints(ids)
+
+	return ids, err
+}
+
+// Snapshots returns the snapshots for a location taken in [from, to), oldest first.
+// A zero to means no upper bound.
+func (s *Store) Snapshots(locationID int, from, to time.Time) ([]Snapshot, error) {
+	var snaps []Snapshot
+	err := s.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket(snapshotsBucket).Bucket(locationKey(locationID))
+		if b == nil {
+			return nil
+		}
+
+		c := b.Cursor()
+		k, v := c.First()
+		if !from.IsZero() {
+			k, v = c.Seek(snapshotKey(from, 0))
+		}
+		for ; k != nil; k, v = c.Next() {
+			var snap Snapshot
+			if err := json.Unmarshal(v, &snap); err != nil {
+				return err
+			}
+			if !to.IsZero() && !snap.Time.Before(to) {
+				break
+			}
+			snaps = append(snaps, snap)
+		}
+		return nil
+	})
+
+	return snaps, err
+}
+
+func locationKey(id int) []byte {
+	return []byte(strconv.Itoa(id))
+}
+
+func snapshotKey(t time.Time, seq uint64) []byte {
+	k := make([]byte, 16)
+	binary.BigEndian.PutUint64(k, uint64(t.UnixNano()))
+	binary.BigEndian.PutUint64(k[8:], seq)
+	return k
+}
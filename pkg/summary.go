@@ -0,0 +1,129 @@
+// Copyright © 2017 Sebastian Mandrean <sebastian.mandrean@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package pkg
+
+import (
+	"context"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"sync"
+	"time"
+)
+
+type (
+	// Summary is the number of available occasions per location per week
+	Summary struct {
+		// Weeks holds the start (Monday) of each week
+		Weeks     []time.Time       `yaml:"weeks"`
+		Locations []LocationSummary `yaml:"locations"`
+	}
+
+	// LocationSummary is the availability at a single location
+	LocationSummary struct {
+		LocationID   int    `yaml:"locationId"`
+		LocationName string `yaml:"locationName"`
+		// Counts holds the number of occasions in each of Summary.Weeks
+		Counts   []int     `yaml:"counts"`
+		Earliest *Occasion `yaml:"earliest"`
+		Error    string    `yaml:"error,omitempty"`
+		// Err is the error Error describes, for telling failures apart
+		Err error `yaml:"-" json:"-"`
+	}
+)
+
+// Summarize aggregates the occasions at each of the locations over the given number of weeks, starting
+// with the current week. body is used as a template for every query, with its LocationID and StartDate overridden.
+func Summarize(ctx context.Context, client Client, body *OccasionBundlesRequest, locationIDs []int, weeks int, parallelism int) *Summary {
+	ctx, span := tracerFor(client).Start(ctx, "Summary", trace.WithAttributes(
+		attribute.IntSlice("trafikverket.location_ids", locationIDs),
+		attribute.Int("trafikverket.weeks", weeks),
+	))
+	defer span.End()
+
+	if parallelism < 1 {
+		parallelism = 1
+	}
+
+	s := &Summary{
+		Locations: make([]LocationSummary, len(locationIDs)),
+	}
+	start := weekStart(time.Now())
+	for i := 0; i < weeks; i++ {
+		s.Weeks = append(s.Weeks, start.AddDate(0, 0, 7*i))
+	}
+
+	sem := make(chan struct{}, parallelism)
+	var wg sync.WaitGroup
+	for i, l := range locationIDs {
+		wg.Add(1)
+		go func(i int, l int) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			s.Locations[i] = summarizeLocation(ctx, client, *body, l, s.Weeks)
+		}(i, l)
+	}
+	wg.Wait()
+
+	return s
+}
+
+// summarizeLocation queries the weeks at a location and counts the occasions per week
+func summarizeLocation(ctx context.Context, client Client, body OccasionBundlesRequest, locationID int, weeks []time.Time) LocationSummary {
+	ls := LocationSummary{
+		LocationID: locationID,
+		Counts:     make([]int, len(weeks)),
+	}
+	if len(weeks) == 0 {
+		return ls
+	}
+	end := weeks[len(weeks)-1].AddDate(0, 0, 7)
+
+	body.OccasionBundleQuery.LocationID = locationID
+	os, err := OccasionsBetween(ctx, client, &body, weeks[0], end)
+	if err != nil {
+		ls.Err = err
+		ls.Error = err.Error()
+		return ls
+	}
+
+	for _, o := range os {
+		w := len(weeks) - 1
+		for o.Duration.Start.Before(weeks[w]) {
+			w--
+		}
+		ls.Counts[w]++
+	}
+	if len(os) > 0 {
+		ls.Earliest = &os[0]
+		ls.LocationName = os[0].LocationName
+	}
+
+	return ls
+}
+
+// weekStart returns midnight on the Monday of t's week
+func weekStart(t time.Time) time.Time {
+	d := (int(t.Weekday()) + 6) % 7
+	y, m, day := t.Date()
+	return time.Date(y, m, day-d, 0, 0, 0, 0, t.Location())
+}
@@ -0,0 +1,56 @@
+// Copyright © 2017 Sebastian Mandrean <sebastian.mandrean@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package pkg
+
+import (
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+const tracerName = "github.com/mandrean/go-trafikverket/pkg"
+
+// WithTracerProvider enables OpenTelemetry tracing of all API calls using tp.
+// Social security numbers are never recorded on spans.
+func WithTracerProvider(tp trace.TracerProvider) ClientOption {
+	return func(tc *TrafikverketClient) {
+		tc.tracer = tp.Tracer(tracerName)
+	}
+}
+
+// tracerFor returns the tracer of client if it is a TrafikverketClient, so functions built on the Client
+// interface are traced like its methods
+func tracerFor(client Client) trace.Tracer {
+	if tc, ok := client.(*TrafikverketClient); ok {
+		return tc.tracer
+	}
+	return noop.NewTracerProvider().Tracer(tracerName)
+}
+
+// endSpan marks span as failed if err is non-nil, and returns err with any personnummer redacted
+func endSpan(span trace.Span, err error) error {
+	if err != nil {
+		err = RedactError(err)
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	return err
+}
@@ -0,0 +1,197 @@
+// Copyright © 2017 Sebastian Mandrean <sebastian.mandrean@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package pkg_test
+
+import (
+	"context"
+	"fmt"
+	"github.com/mandrean/go-trafikverket/pkg"
+	"github.com/mandrean/go-trafikverket/pkg/trafikverkettest"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testSSN is the personnummer of the test candidate
+const testSSN = "199001010017"
+
+// recordSpans returns a client for s recording its spans, and the recorder
+func recordSpans(s *trafikverkettest.Server, opts ...pkg.ClientOption) (*pkg.TrafikverketClient, *sdktrace.TracerProvider, *tracetest.SpanRecorder) {
+	sr := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sr))
+	return s.Client(append([]pkg.ClientOption{pkg.WithTracerProvider(tp)}, opts...)...), tp, sr
+}
+
+// spanNamed returns the ended span called name
+func spanNamed(t *testing.T, sr *tracetest.SpanRecorder, name string) sdktrace.ReadOnlySpan {
+	t.Helper()
+	for _, s := range sr.Ended() {
+		if s.Name() == name {
+			return s
+		}
+	}
+	t.Fatalf("no span named %q", name)
+	return nil
+}
+
+// attr returns the value of the attribute key of s, or "" if it isn't set
+func attr(s sdktrace.ReadOnlySpan, key string) string {
+	for _, kv := range s.Attributes() {
+		if string(kv.Key) == key {
+			return kv.Value.Emit()
+		}
+	}
+	return ""
+}
+
+// assertNoPersonnummer fails if any attribute, status or event of the recorded spans contains ssn,
+// in its 12 or 10 digit form
+func assertNoPersonnummer(t *testing.T, sr *tracetest.SpanRecorder, ssn string) {
+	t.Helper()
+	leaks := func(s string) bool {
+		return strings.Contains(s, ssn) || strings.Contains(s, ssn[2:])
+	}
+	for _, s := range sr.Ended() {
+		for _, kv := range s.Attributes() {
+			if leaks(kv.Value.Emit()) {
+				t.Errorf("span %q attribute %v contains the personnummer: %v", s.Name(), kv.Key, kv.Value.Emit())
+			}
+		}
+		if leaks(s.Status().Description) {
+			t.Errorf("span %q status contains the personnummer: %v", s.Name(), s.Status().Description)
+		}
+		for _, e := range s.Events() {
+			for _, kv := range e.Attributes {
+				if leaks(kv.Value.Emit()) {
+					t.Errorf("span %q event %q contains the personnummer: %v", s.Name(), e.Name, kv.Value.Emit())
+				}
+			}
+		}
+	}
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestTracingOccasions(t *testing.T) {
+	s := trafikverkettest.NewServer(trafikverkettest.DefaultFixtures(time.Now()))
+	defer s.Close()
+	tc, tp, sr := recordSpans(s)
+
+	ctx, command := tp.Tracer("test").Start(context.Background(), "command")
+	os, _, err := tc.OccasionsContext(ctx, &pkg.OccasionBundlesRequest{
+		BookingSession:      pkg.BookingSession{SocialSecurityNumber: testSSN, LicenceID: pkg.DefaultLicenceID},
+		OccasionBundleQuery: pkg.OccasionBundleQuery{LocationID: 1000140},
+	})
+	command.End()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	root := spanNamed(t, sr, "command")
+	occasions := spanNamed(t, sr, "Occasions")
+	post := spanNamed(t, sr, "POST /occasion-bundles")
+
+	// command > Occasions > POST /occasion-bundles
+	if occasions.Parent().SpanID() != root.SpanContext().SpanID() {
+		t.Errorf("Occasions isn't a child of command")
+	}
+	if post.Parent().SpanID() != occasions.SpanContext().SpanID() {
+		t.Errorf("POST /occasion-bundles isn't a child of Occasions")
+	}
+
+	for _, tt := range []struct {
+		span sdktrace.ReadOnlySpan
+		key  string
+		want string
+	}{
+		{occasions, "trafikverket.location_id", "1000140"},
+		{occasions, "trafikverket.results", strconv.Itoa(len(*os))},
+		{post, "trafikverket.endpoint", "occasion-bundles"},
+		{post, "http.request.method", "POST"},
+		{post, "http.response.status_code", "200"},
+	} {
+		if got := attr(tt.span, tt.key); got != tt.want {
+			t.Errorf("%v %v = %q, want %q", tt.span.Name(), tt.key, got, tt.want)
+		}
+	}
+	for _, key := range []string{"http.request.body.size", "http.response.body.size"} {
+		if got := attr(post, key); got == "" || got == "0" {
+			t.Errorf("%v %v = %q, want a size", post.Name(), key, got)
+		}
+	}
+	if post.Status().Code == codes.Error {
+		t.Errorf("%v status = %v, want no error", post.Name(), post.Status())
+	}
+
+	assertNoPersonnummer(t, sr, testSSN)
+}
+
+func TestTracingErrors(t *testing.T) {
+	s := trafikverkettest.NewServer(trafikverkettest.DefaultFixtures(time.Now()))
+	defer s.Close()
+	tc, _, sr := recordSpans(s)
+
+	// an upstream failure
+	s.FailNext("search-information", http.StatusInternalServerError)
+	_, _, err := tc.Locations(&pkg.SearchInformationRequest{
+		BookingSession: pkg.BookingSession{SocialSecurityNumber: testSSN, LicenceID: pkg.DefaultLicenceID},
+	})
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+
+	post := spanNamed(t, sr, "POST /search-information")
+	if got := attr(post, "http.response.status_code"); got != "500" {
+		t.Errorf("status code = %q, want 500", got)
+	}
+	for _, name := range []string{"POST /search-information", "Locations"} {
+		if s := spanNamed(t, sr, name); s.Status().Code != codes.Error || len(s.Events()) == 0 {
+			t.Errorf("%v: status = %v with %d events, want a recorded error", name, s.Status(), len(s.Events()))
+		}
+	}
+
+	assertNoPersonnummer(t, sr, testSSN)
+
+	// a transport error echoing the request body, and with it the personnummer
+	tc, _, sr = recordSpans(s, pkg.WithRoundTripper(func(http.RoundTripper) http.RoundTripper {
+		return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			b, _ := io.ReadAll(req.Body)
+			return nil, fmt.Errorf("proxy rejected %s", b)
+		})
+	}))
+	if _, _, err := tc.Locations(&pkg.SearchInformationRequest{
+		BookingSession: pkg.BookingSession{SocialSecurityNumber: testSSN, LicenceID: pkg.DefaultLicenceID},
+	}); err == nil || strings.Contains(err.Error(), testSSN) {
+		t.Fatalf("err = %v, want a redacted error", err)
+	}
+
+	assertNoPersonnummer(t, sr, testSSN)
+}
@@ -0,0 +1,145 @@
+// Copyright © 2017 Sebastian Mandrean <sebastian.mandrean@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package trafikverkettest
+
+import (
+	"github.com/mandrean/go-trafikverket/pkg"
+	"time"
+)
+
+const (
+	// DrivingExaminationTypeID is the examination type of the driving test (körprov) in DefaultFixtures
+	DrivingExaminationTypeID = 12
+	// TheoryExaminationTypeID is the examination type of the theory test (kunskapsprov) in DefaultFixtures
+	TheoryExaminationTypeID = 3
+)
+
+type (
+	// Fixtures is the data served by a fake server. The first entry of each of the
+	// ID lists is the default returned by search-information.
+	Fixtures struct {
+		LicenceCategories []pkg.LicenceCategory
+		Locations         []pkg.Location
+		Languages         []pkg.Language
+		VehicleTypes      []pkg.VehicleType
+		TachographTypes   []pkg.TachographType
+		OccasionChoices   []pkg.OccasionChoice
+		ExaminationTypes  []pkg.ExaminationType
+		Occasions         []pkg.Occasion
+
+		// Combined makes occasion-bundles answer queries without an examination type with bundles pairing
+		// each theory test with the first driving test on a later day at the same location, like
+		// Trafikverket does for candidates who have yet to pass the theory test
+		Combined bool
+	}
+)
+
+// DefaultFixtures returns fixtures for licence B at three locations, with driving and theory
+// tests every weekday for six weeks after now
+func DefaultFixtures(now time.Time) Fixtures {
+	f := Fixtures{
+		LicenceCategories: []pkg.LicenceCategory{{
+			Name: "Personbil",
+			Licences: []pkg.Licence{{
+				ID:          pkg.DefaultLicenceID,
+				Name:        "B",
+				Description: "Personbil och lätt lastbil",
+				Category:    "Personbil",
+			}},
+		}},
+		Languages: []pkg.Language{
+			{ID: 13, Name: "Svenska"},
+			{ID: 4, Name: "Engelska", LocationIDs: []int{1000140}},
+		},
+		VehicleTypes: []pkg.VehicleType{
+			{ID: 1, Name: "Manuell"},
+			{ID: 4, Name: "Automat"},
+		},
+		TachographTypes: []pkg.TachographType{
+			{ID: 1, Name: "Ej aktuellt"},
+		},
+		OccasionChoices: []pkg.OccasionChoice{
+			{ID: 1, Name: "Boka prov"},
+		},
+		ExaminationTypes: []pkg.ExaminationType{
+			{ID: DrivingExaminationTypeID, Name: "Körprov B"},
+			{ID: TheoryExaminationTypeID, Name: "Kunskapsprov B"},
+		},
+	}
+
+	for _, l := range []struct {
+		id       uint64
+		name     string
+		street   string
+		zip      string
+		lat, lon float64
+	}{
+		{1000140, "Stockholm", "Pyramidvägen 7", "169 56", 59.3647, 18.0061},
+		{1000071, "Göteborg", "Ringögatan 12", "417 07", 57.7233, 11.9526},
+		{1000072, "Malmö", "Krusegatan 19", "212 25", 55.5815, 13.0245},
+	} {
+		var loc pkg.Location
+		loc.ID = l.id
+		loc.Name = l.name
+		loc.Address.StreetAddress1 = l.street
+		loc.Address.ZipCode = l.zip
+		loc.Address.City = l.name
+		loc.Coordinates.Latitude = l.lat
+		loc.Coordinates.Longitude = l.lon
+		f.Locations = append(f.Locations, loc)
+	}
+
+	start := time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, time.Local)
+	for d := 0; d < 6*7; d++ {
+		day := start.AddDate(0, 0, d)
+		if day.Weekday() == time.Saturday || day.Weekday() == time.Sunday {
+			continue
+		}
+		for _, l := range f.Locations {
+			f.Occasions = append(f.Occasions,
+				occasion(l, DrivingExaminationTypeID, "Körprov B", "800 kr", day.Add(8*time.Hour), 45*time.Minute),
+				occasion(l, DrivingExaminationTypeID, "Körprov B", "800 kr", day.Add(13*time.Hour), 45*time.Minute),
+				occasion(l, TheoryExaminationTypeID, "Kunskapsprov B", "325 kr", day.Add(10*time.Hour), 50*time.Minute),
+			)
+		}
+	}
+
+	return f
+}
+
+func occasion(l pkg.Location, examinationTypeID int, name string, cost string, start time.Time, d time.Duration) pkg.Occasion {
+	var o pkg.Occasion
+	o.Duration.Start = start
+	o.Duration.End = start.Add(d)
+	o.ExaminationTypeID = examinationTypeID
+	o.LocationID = int(l.ID)
+	o.OccasionChoiceID = 1
+	o.VehicleTypeID = 1
+	o.LanguageID = 13
+	o.TachographTypeID = 1
+	o.Name = name
+	o.Date = start.Format("2006-01-02")
+	o.Time = start.Format("15:04")
+	o.LocationName = l.Name
+	o.Cost = cost
+	o.PlaceAddress = l.Address.StreetAddress1 + ", " + l.Address.City
+	return o
+}
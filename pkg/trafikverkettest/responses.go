@@ -0,0 +1,177 @@
+// Copyright © 2017 Sebastian Mandrean <sebastian.mandrean@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package trafikverkettest
+
+import (
+	"fmt"
+	"github.com/mandrean/go-trafikverket/pkg"
+	"github.com/mandrean/go-trafikverket/pkg/personnummer"
+	"net/http"
+	"sort"
+	"time"
+)
+
+// licenceInformation builds the licence-information response for f
+func licenceInformation(f Fixtures) *pkg.LicenceInformationResponse {
+	var resp pkg.LicenceInformationResponse
+	resp.Data.LicenceID = pkg.DefaultLicenceID
+	resp.Data.LicenceCategories = f.LicenceCategories
+	resp.Status = http.StatusOK
+	resp.URL = "/Boka/licence-information"
+	return &resp
+}
+
+// searchInformation builds the search-information response for f
+func searchInformation(f Fixtures, body *pkg.SearchInformationRequest) (*pkg.SearchInformationResponse, error) {
+	if err := validate(body.BookingSession); err != nil {
+		return nil, err
+	}
+
+	var resp pkg.SearchInformationResponse
+	resp.Data.CanBookLicence = true
+	resp.Data.LicenceID = uint64(body.BookingSession.LicenceID)
+	resp.Data.LicenceCategories = f.LicenceCategories
+	resp.Data.Locations = f.Locations
+	resp.Data.Languages = f.Languages
+	resp.Data.ShowLanguage = len(f.Languages) > 1
+	resp.Data.VehicleTypes = f.VehicleTypes
+	resp.Data.ShowVehicleType = len(f.VehicleTypes) > 1
+	resp.Data.TachographTypes = f.TachographTypes
+	resp.Data.ShowTachographType = len(f.TachographTypes) > 1
+	resp.Data.OccasionChoices = f.OccasionChoices
+	resp.Data.ShowOccasionChoices = len(f.OccasionChoices) > 1
+	resp.Data.ExaminationTypes = f.ExaminationTypes
+	resp.Data.ShowExaminationType = len(f.ExaminationTypes) > 1
+
+	// the first of each is the default
+	if len(f.Languages) > 0 {
+		resp.Data.LanguageID = f.Languages[0].ID
+	}
+	if len(f.VehicleTypes) > 0 {
+		resp.Data.VehicleTypeID = uint64(f.VehicleTypes[0].ID)
+	}
+	if len(f.TachographTypes) > 0 {
+		resp.Data.TachographTypeID = uint64(f.TachographTypes[0].ID)
+	}
+	if len(f.OccasionChoices) > 0 {
+		resp.Data.OccasionChoiceID = f.OccasionChoices[0].ID
+	}
+	if len(f.ExaminationTypes) > 0 {
+		resp.Data.ExaminationTypeID = f.ExaminationTypes[0].ID
+	}
+
+	resp.Status = http.StatusOK
+	resp.URL = "/Boka/search-information"
+	return &resp, nil
+}
+
+// occasionBundles builds the occasion-bundles response for f, with one bundle per occasion
+// starting within window of the query's start date
+func occasionBundles(f Fixtures, window time.Duration, body *pkg.OccasionBundlesRequest) (*pkg.OccasionBundlesResponse, error) {
+	if err := validate(body.BookingSession); err != nil {
+		return nil, err
+	}
+	q := body.OccasionBundleQuery
+	if q.LocationID == 0 {
+		return nil, fmt.Errorf("locationId is required")
+	}
+
+	if f.Combined && q.ExaminationTypeID == 0 {
+		return combinedBundles(f, window, q)
+	}
+
+	examinationTypeID := q.ExaminationTypeID
+	if examinationTypeID == 0 && len(f.ExaminationTypes) > 0 {
+		examinationTypeID = int(f.ExaminationTypes[0].ID)
+	}
+
+	var resp pkg.OccasionBundlesResponse
+	resp.Data = []pkg.Bundle{}
+	for _, o := range occasions(f, window, q, examinationTypeID) {
+		resp.Data = append(resp.Data, pkg.Bundle{Occasions: []pkg.Occasion{o}, Cost: o.Cost})
+	}
+	resp.Status = http.StatusOK
+	resp.URL = "/Boka/occasion-bundles"
+	return &resp, nil
+}
+
+// combinedBundles pairs each theory test within window of the query's start date with the first
+// driving test on a later day
+func combinedBundles(f Fixtures, window time.Duration, q pkg.OccasionBundleQuery) (*pkg.OccasionBundlesResponse, error) {
+	driving := occasions(f, 0, q, DrivingExaminationTypeID)
+
+	var resp pkg.OccasionBundlesResponse
+	resp.Data = []pkg.Bundle{}
+	for _, t := range occasions(f, window, q, TheoryExaminationTypeID) {
+		y, m, d := t.Duration.Start.Date()
+		next := time.Date(y, m, d+1, 0, 0, 0, 0, t.Duration.Start.Location())
+		for _, o := range driving {
+			if o.Duration.Start.Before(next) {
+				continue
+			}
+			b := pkg.Bundle{Occasions: []pkg.Occasion{t, o}}
+			cost, err := b.TotalCost()
+			if err != nil {
+				return nil, err
+			}
+			b.Cost = fmt.Sprintf("%d kr", cost)
+			resp.Data = append(resp.Data, b)
+			break
+		}
+	}
+	resp.Status = http.StatusOK
+	resp.URL = "/Boka/occasion-bundles"
+	return &resp, nil
+}
+
+// occasions returns the occasions of the examination type at the query's location starting within window of
+// the query's start date, or at any time after it if window is 0, sorted by start
+func occasions(f Fixtures, window time.Duration, q pkg.OccasionBundleQuery, examinationTypeID int) []pkg.Occasion {
+	from := q.StartDate
+	if now := time.Now(); from.Before(now) {
+		from = now
+	}
+	to := from.Add(window)
+
+	var os []pkg.Occasion
+	for _, o := range f.Occasions {
+		if o.LocationID != q.LocationID || o.ExaminationTypeID != examinationTypeID {
+			continue
+		}
+		if o.Duration.Start.Before(from) || (window > 0 && !o.Duration.Start.Before(to)) {
+			continue
+		}
+		os = append(os, o)
+	}
+	sort.Slice(os, func(i, j int) bool {
+		return os[i].Duration.Start.Before(os[j].Duration.Start)
+	})
+
+	return os
+}
+
+// validate checks the booking session like Trafikverket does
+func validate(bs pkg.BookingSession) error {
+	if !personnummer.Valid(bs.SocialSecurityNumber) {
+		return fmt.Errorf("invalid socialSecurityNumber")
+	}
+	return nil
+}
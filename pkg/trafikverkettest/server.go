@@ -0,0 +1,186 @@
+// Copyright © 2017 Sebastian Mandrean <sebastian.mandrean@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package trafikverkettest provides an in-process fake of the Förarprov API, for testing code built on pkg
+// without calling Trafikverket.
+package trafikverkettest
+
+import (
+	"encoding/json"
+	"github.com/mandrean/go-trafikverket/pkg"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultWindow is how far past the start date occasion-bundles returns occasions
+const DefaultWindow = 14 * 24 * time.Hour
+
+type (
+	// Server is a fake Förarprov API serving Fixtures
+	Server struct {
+		*httptest.Server
+
+		mu       sync.Mutex
+		fixtures Fixtures
+		window   time.Duration
+		latency  time.Duration
+		failures map[string][]int
+		requests map[string]int
+
+		// rate limiting
+		rateLimit int
+		ratePer   time.Duration
+		rateStart time.Time
+		rateCount int
+	}
+)
+
+// NewServer starts a fake server serving f. Close it when done.
+func NewServer(f Fixtures) *Server {
+	s := &Server{
+		fixtures: f,
+		window:   DefaultWindow,
+		failures: make(map[string][]int),
+		requests: make(map[string]int),
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.handle))
+	return s
+}
+
+// Client returns a client using the fake server
+func (s *Server) Client(opts ...pkg.ClientOption) *pkg.TrafikverketClient {
+	return pkg.NewClient(append([]pkg.ClientOption{pkg.WithBaseURL(s.URL)}, opts...)...)
+}
+
+// SetFixtures replaces the data served
+func (s *Server) SetFixtures(f Fixtures) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.fixtures = f
+}
+
+// SetWindow sets how far past the start date occasion-bundles returns occasions
+func (s *Server) SetWindow(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.window = d
+}
+
+// SetLatency delays every response by d
+func (s *Server) SetLatency(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.latency = d
+}
+
+// SetRateLimit makes the server respond 429 Too Many Requests to more than n requests per period.
+// A zero n disables rate limiting.
+func (s *Server) SetRateLimit(n int, per time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rateLimit, s.ratePer = n, per
+	s.rateStart, s.rateCount = time.Time{}, 0
+}
+
+// FailNext makes the next request to endpoint, e.g. "occasion-bundles", respond with status.
+// Calling it repeatedly queues up failures for subsequent requests.
+func (s *Server) FailNext(endpoint string, status int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failures[endpoint] = append(s.failures[endpoint], status)
+}
+
+// Requests returns the number of requests made to endpoint, e.g. "occasion-bundles"
+func (s *Server) Requests(endpoint string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests[endpoint]
+}
+
+func (s *Server) handle(w http.ResponseWriter, r *http.Request) {
+	endpoint := strings.TrimPrefix(r.URL.Path, "/Boka/")
+
+	// pick up settings and count the request
+	s.mu.Lock()
+	s.requests[endpoint]++
+	f, window, latency := s.fixtures, s.window, s.latency
+	status := 0
+	if q := s.failures[endpoint]; len(q) > 0 {
+		status, s.failures[endpoint] = q[0], q[1:]
+	}
+	if s.rateLimit > 0 {
+		now := time.Now()
+		if now.Sub(s.rateStart) >= s.ratePer {
+			s.rateStart, s.rateCount = now, 0
+		}
+		s.rateCount++
+		if s.rateCount > s.rateLimit {
+			status = http.StatusTooManyRequests
+		}
+	}
+	s.mu.Unlock()
+
+	if latency > 0 {
+		select {
+		case <-time.After(latency):
+		case <-r.Context().Done():
+			return
+		}
+	}
+
+	if status != 0 {
+		http.Error(w, http.StatusText(status), status)
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var resp interface{}
+	var err error
+	switch endpoint {
+	case "licence-information":
+		resp = licenceInformation(f)
+	case "search-information":
+		var body pkg.SearchInformationRequest
+		if err = json.NewDecoder(r.Body).Decode(&body); err == nil {
+			resp, err = searchInformation(f, &body)
+		}
+	case "occasion-bundles":
+		var body pkg.OccasionBundlesRequest
+		if err = json.NewDecoder(r.Body).Decode(&body); err == nil {
+			resp, err = occasionBundles(f, window, &body)
+		}
+	default:
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,194 @@
+// Copyright © 2017 Sebastian Mandrean <sebastian.mandrean@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package trafikverkettest
+
+import (
+	"context"
+	"github.com/mandrean/go-trafikverket/pkg"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// StaticClient is an in-memory pkg.Client answering from Fixtures like Server does, but without any
+// HTTP round trip. The *http.Response it returns is synthetic, with only the status set.
+type StaticClient struct {
+	mu       sync.Mutex
+	fixtures Fixtures
+	window   time.Duration
+}
+
+var _ pkg.Client = (*StaticClient)(nil)
+
+// NewStaticClient creates a StaticClient answering from f
+func NewStaticClient(f Fixtures) *StaticClient {
+	return &StaticClient{fixtures: f, window: DefaultWindow}
+}
+
+// SetFixtures replaces the data answered with
+func (c *StaticClient) SetFixtures(f Fixtures) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.fixtures = f
+}
+
+// SetWindow sets how far past the start date OccasionBundles returns occasions
+func (c *StaticClient) SetWindow(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.window = d
+}
+
+// snapshot returns the current fixtures and window
+func (c *StaticClient) snapshot() (Fixtures, time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.fixtures, c.window
+}
+
+// response returns a synthetic response with status, or the context's error
+func response(ctx context.Context, status int) (*http.Response, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return &http.Response{
+		Status:     http.StatusText(status),
+		StatusCode: status,
+		Header:     make(http.Header),
+		Body:       http.NoBody,
+	}, nil
+}
+
+// LicenceInformation implements pkg.Client
+func (c *StaticClient) LicenceInformation() (*pkg.LicenceInformationResponse, *http.Response, error) {
+	return c.LicenceInformationContext(context.Background())
+}
+
+// LicenceInformationContext implements pkg.Client
+func (c *StaticClient) LicenceInformationContext(ctx context.Context) (*pkg.LicenceInformationResponse, *http.Response, error) {
+	res, err := response(ctx, http.StatusOK)
+	if err != nil {
+		return nil, nil, err
+	}
+	f, _ := c.snapshot()
+	return licenceInformation(f), res, nil
+}
+
+// LicenceCategories implements pkg.Client
+func (c *StaticClient) LicenceCategories() (*[]pkg.LicenceCategory, *http.Response, error) {
+	return c.LicenceCategoriesContext(context.Background())
+}
+
+// LicenceCategoriesContext implements pkg.Client
+func (c *StaticClient) LicenceCategoriesContext(ctx context.Context) (*[]pkg.LicenceCategory, *http.Response, error) {
+	resp, res, err := c.LicenceInformationContext(ctx)
+	if err != nil {
+		return nil, res, err
+	}
+	return &resp.Data.LicenceCategories, res, nil
+}
+
+// SearchInformation implements pkg.Client
+func (c *StaticClient) SearchInformation(body *pkg.SearchInformationRequest) (*pkg.SearchInformationResponse, *http.Response, error) {
+	return c.SearchInformationContext(context.Background(), body)
+}
+
+// SearchInformationContext implements pkg.Client
+func (c *StaticClient) SearchInformationContext(ctx context.Context, body *pkg.SearchInformationRequest) (*pkg.SearchInformationResponse, *http.Response, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, nil, err
+	}
+	f, _ := c.snapshot()
+	resp, err := searchInformation(f, body)
+	if err != nil {
+		res, _ := response(ctx, http.StatusBadRequest)
+		return nil, res, err
+	}
+	res, err := response(ctx, http.StatusOK)
+	return resp, res, err
+}
+
+// Locations implements pkg.Client
+func (c *StaticClient) Locations(body *pkg.SearchInformationRequest) (*[]pkg.Location, *http.Response, error) {
+	return c.LocationsContext(context.Background(), body)
+}
+
+// LocationsContext implements pkg.Client
+func (c *StaticClient) LocationsContext(ctx context.Context, body *pkg.SearchInformationRequest) (*[]pkg.Location, *http.Response, error) {
+	resp, res, err := c.SearchInformationContext(ctx, body)
+	if err != nil {
+		return nil, res, err
+	}
+	return &resp.Data.Locations, res, nil
+}
+
+// OccasionBundles implements pkg.Client
+func (c *StaticClient) OccasionBundles(body *pkg.OccasionBundlesRequest) (*pkg.OccasionBundlesResponse, *http.Response, error) {
+	return c.OccasionBundlesContext(context.Background(), body)
+}
+
+// OccasionBundlesContext implements pkg.Client
+func (c *StaticClient) OccasionBundlesContext(ctx context.Context, body *pkg.OccasionBundlesRequest) (*pkg.OccasionBundlesResponse, *http.Response, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, nil, err
+	}
+	f, window := c.snapshot()
+	resp, err := occasionBundles(f, window, body)
+	if err != nil {
+		res, _ := response(ctx, http.StatusBadRequest)
+		return nil, res, err
+	}
+	res, err := response(ctx, http.StatusOK)
+	return resp, res, err
+}
+
+// Occasions implements pkg.Client
+func (c *StaticClient) Occasions(body *pkg.OccasionBundlesRequest) (*[]pkg.Occasion, *http.Response, error) {
+	return c.OccasionsContext(context.Background(), body)
+}
+
+// OccasionsContext implements pkg.Client
+func (c *StaticClient) OccasionsContext(ctx context.Context, body *pkg.OccasionBundlesRequest) (*[]pkg.Occasion, *http.Response, error) {
+	resp, res, err := c.OccasionBundlesContext(ctx, body)
+	if err != nil {
+		return nil, res, err
+	}
+
+	var os []pkg.Occasion
+	for _, b := range resp.Data {
+		os = append(os, b.Occasions...)
+	}
+	return &os, res, nil
+}
+
+// Bundles implements pkg.Client
+func (c *StaticClient) Bundles(body *pkg.OccasionBundlesRequest) (*[]pkg.Bundle, *http.Response, error) {
+	return c.BundlesContext(context.Background(), body)
+}
+
+// BundlesContext implements pkg.Client
+func (c *StaticClient) BundlesContext(ctx context.Context, body *pkg.OccasionBundlesRequest) (*[]pkg.Bundle, *http.Response, error) {
+	resp, res, err := c.OccasionBundlesContext(ctx, body)
+	if err != nil {
+		return nil, res, err
+	}
+	return &resp.Data, res, nil
+}
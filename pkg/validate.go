@@ -0,0 +1,183 @@
+// Copyright © 2017 Sebastian Mandrean <sebastian.mandrean@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package pkg
+
+import (
+	"context"
+	"fmt"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"strings"
+)
+
+type (
+	// ValidationError lists why an OccasionBundleQuery is invalid for a booking session
+	ValidationError struct {
+		Problems []Problem `yaml:"problems"`
+	}
+
+	// Problem is an invalid field of an OccasionBundleQuery
+	Problem struct {
+		// Field is the yaml/json name of the field, e.g. languageId
+		Field   string `yaml:"field" json:"field"`
+		Message string `yaml:"message" json:"message"`
+	}
+)
+
+func (e *ValidationError) Error() string {
+	ps := make([]string, len(e.Problems))
+	for i, p := range e.Problems {
+		ps[i] = p.Field + ": " + p.Message
+	}
+	return "invalid occasion query: " + strings.Join(ps, "; ")
+}
+
+// ValidateQuery checks the query of body against the search information for its booking session, returning
+// a *ValidationError if any of its IDs are unknown or not offered together, before OccasionBundles is called
+// with it and Trafikverket responds with an opaque error or no occasions.
+func ValidateQuery(ctx context.Context, client Client, body *OccasionBundlesRequest) error {
+	ctx, span := tracerFor(client).Start(ctx, "ValidateQuery", trace.WithAttributes(
+		attribute.Int("trafikverket.licence_id", body.BookingSession.LicenceID),
+		attribute.Int("trafikverket.location_id", body.OccasionBundleQuery.LocationID),
+	))
+	defer span.End()
+
+	info, err := searchInformationFor(ctx, client, body)
+	if err != nil {
+		return endSpan(span, err)
+	}
+
+	if err := CheckQuery(info, body.OccasionBundleQuery); err != nil {
+		return endSpan(span, err)
+	}
+	return nil
+}
+
+// searchInformationFor returns the search information for the booking session of body, for the examination
+// type of its query if it has one, as locations and languages depend on the examination type
+func searchInformationFor(ctx context.Context, client Client, body *OccasionBundlesRequest) (*SearchInformationResponse, error) {
+	bs := body.BookingSession
+	if q := body.OccasionBundleQuery; q.ExaminationTypeID != 0 {
+		bs.ExaminationTypeID = q.ExaminationTypeID
+	}
+	info, _, err := client.SearchInformationContext(ctx, &SearchInformationRequest{BookingSession: bs})
+	return info, err
+}
+
+// CheckQuery checks the IDs of q against info like ValidateQuery. IDs of 0 are left for Trafikverket to
+// default, except for the required LocationID. Lists info has no entries for are not checked.
+func CheckQuery(info *SearchInformationResponse, q OccasionBundleQuery) error {
+	d := info.Data
+	var ps []Problem
+	add := func(field string, format string, args ...interface{}) {
+		ps = append(ps, Problem{Field: field, Message: fmt.Sprintf(format, args...)})
+	}
+
+	var location *Location
+	for i, l := range d.Locations {
+		if int(l.ID) == q.LocationID {
+			location = &d.Locations[i]
+		}
+	}
+	switch {
+	case q.LocationID == 0:
+		add("locationId", "a location is required, see list locations")
+	case location == nil:
+		add("locationId", "location %d is not offered for licence %d, see list locations", q.LocationID, d.LicenceID)
+	}
+
+	if q.ExaminationTypeID != 0 && len(d.ExaminationTypes) > 0 {
+		var valid []string
+		found := false
+		for _, e := range d.ExaminationTypes {
+			found = found || int(e.ID) == q.ExaminationTypeID
+			valid = append(valid, option(int(e.ID), e.Name))
+		}
+		if !found {
+			add("examinationTypeId", "examination type %d does not exist, valid: %v", q.ExaminationTypeID, strings.Join(valid, ", "))
+		}
+	}
+
+	if q.LanguageID != 0 && len(d.Languages) > 0 {
+		var language *Language
+		var valid []string
+		for i, l := range d.Languages {
+			if int(l.ID) == q.LanguageID {
+				language = &d.Languages[i]
+			}
+			if location == nil || offers(l, q.LocationID) {
+				valid = append(valid, option(int(l.ID), l.Name))
+			}
+		}
+		switch {
+		case language == nil:
+			add("languageId", "language %d does not exist, valid: %v", q.LanguageID, strings.Join(valid, ", "))
+		case location != nil && !offers(*language, q.LocationID):
+			add("languageId", "%v is not offered at %v, valid there: %v", language.Name, location.Name, strings.Join(valid, ", "))
+		}
+	}
+
+	if q.VehicleTypeID != 0 && len(d.VehicleTypes) > 0 {
+		var valid []string
+		found := false
+		for _, v := range d.VehicleTypes {
+			found = found || v.ID == q.VehicleTypeID
+			valid = append(valid, option(v.ID, v.Name))
+		}
+		if !found {
+			add("vehicleTypeId", "vehicle type %d does not exist, valid: %v", q.VehicleTypeID, strings.Join(valid, ", "))
+		}
+	}
+
+	if q.TachographTypeID != 0 && len(d.TachographTypes) > 0 {
+		var valid []string
+		found := false
+		for _, t := range d.TachographTypes {
+			found = found || t.ID == q.TachographTypeID
+			valid = append(valid, option(t.ID, t.Name))
+		}
+		if !found {
+			add("tachographTypeId", "tachograph type %d does not exist, valid: %v", q.TachographTypeID, strings.Join(valid, ", "))
+		}
+	}
+
+	if q.OccasionChoiceID != 0 && len(d.OccasionChoices) > 0 {
+		var valid []string
+		found := false
+		for _, o := range d.OccasionChoices {
+			found = found || int(o.ID) == q.OccasionChoiceID
+			valid = append(valid, option(int(o.ID), o.Name))
+		}
+		if !found {
+			add("occasionChoiceId", "occasion choice %d does not exist, valid: %v", q.OccasionChoiceID, strings.Join(valid, ", "))
+		}
+	}
+
+	if len(ps) > 0 {
+		return &ValidationError{Problems: ps}
+	}
+	return nil
+}
+
+// option formats an ID and its name for listing valid options
+func option(id int, name string) string {
+	return fmt.Sprintf("%d (%v)", id, name)
+}